@@ -0,0 +1,53 @@
+package pretty
+
+import (
+	"strings"
+	"testing"
+)
+
+// assertMaxDiffs is the default number of differences AssertEqual and
+// RequireEqual report. WithMaxDiffs among their options overrides it.
+const assertMaxDiffs = 100
+
+// AssertEqual compares want and got with a Comparator configured by opts
+// and marks t as failed with a report of the differences if there are
+// any. Each difference is prefixed with its labels, see WithLabelFields.
+// It reports whether want and got are equal.
+func AssertEqual(t testing.TB, want, got interface{}, opts ...func(*Options)) bool {
+	t.Helper()
+	if report, ok := equalReport(want, got, opts); !ok {
+		t.Errorf("%s", report)
+		return false
+	}
+	return true
+}
+
+// RequireEqual is like AssertEqual, but stops the test with t.FailNow
+// if want and got differ.
+func RequireEqual(t testing.TB, want, got interface{}, opts ...func(*Options)) {
+	t.Helper()
+	if report, ok := equalReport(want, got, opts); !ok {
+		t.Fatalf("%s", report)
+	}
+}
+
+func equalReport(want, got interface{}, opts []func(*Options)) (string, bool) {
+	c := NewCustomDiff(append([]func(*Options){WithMaxDiffs(assertMaxDiffs)}, opts...)...)
+	diffs, ok := c.StructuredDiff(want, got)
+	if ok {
+		return "", true
+	}
+	var b strings.Builder
+	b.WriteString("values differ (want != got):")
+	for _, d := range diffs {
+		b.WriteString("\n\t")
+		if len(setLabels(d.Labels)) > 0 {
+			b.WriteString("[" + labelsKey(d.Labels) + "] ")
+		}
+		if d.FieldName != "" {
+			b.WriteString(d.FieldName + ": ")
+		}
+		b.WriteString(d.ValueA + " != " + d.ValueB)
+	}
+	return b.String(), false
+}
@@ -0,0 +1,68 @@
+package pretty
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingTB records the failures reported to it.
+type recordingTB struct {
+	testing.TB
+	errors []string
+	fatal  bool
+}
+
+func (r *recordingTB) Helper() {}
+
+func (r *recordingTB) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func (r *recordingTB) Fatalf(format string, args ...interface{}) {
+	r.Errorf(format, args...)
+	r.fatal = true
+}
+
+func TestAssertEqual(t *testing.T) {
+	type row struct {
+		ID    string
+		Price float64
+		Qty   int
+	}
+	want := []row{{"a", 1, 1}, {"b", 2, 2}}
+	got := []row{{"a", 1.001, 1}, {"b", 2.5, 3}}
+
+	r := &recordingTB{TB: t}
+	assert.False(t, AssertEqual(r, want, got, WithLabelFields("ID")))
+	assert.Equal(t, []string{`values differ (want != got):
+	[ID=a] [0].Price: 1 != 1.001
+	[ID=b] [1].Price: 2 != 2.5
+	[ID=b] [1].Qty: 2 != 3`}, r.errors)
+	assert.False(t, r.fatal)
+
+	r = &recordingTB{TB: t}
+	assert.True(t, AssertEqual(r, want, want))
+	assert.True(t, AssertEqual(r, row{Price: 1}, row{Price: 1.001}, WithNumericEpsilon(0.01)))
+	assert.Empty(t, r.errors)
+
+	type batch struct {
+		Name string
+		Rows []row
+	}
+	r = &recordingTB{TB: t}
+	AssertEqual(r, batch{"x", want}, batch{"y", want}, WithLabelFields("ID"))
+	assert.Equal(t, []string{"values differ (want != got):\n\tName: \"x\" != \"y\""}, r.errors, "no empty labels")
+
+	r = &recordingTB{TB: t}
+	RequireEqual(r, 1, 2, WithMaxDiffs(1))
+	assert.Equal(t, []string{"values differ (want != got):\n\t1 != 2"}, r.errors)
+	assert.True(t, r.fatal)
+
+	r = &recordingTB{TB: t}
+	RequireEqual(r, want, want)
+	assert.False(t, r.fatal)
+}
+
+func (r *recordingTB) Logf(format string, args ...interface{}) {}
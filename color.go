@@ -0,0 +1,81 @@
+package pretty
+
+import (
+	"io"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+// ColorMode tells Print, Fprintf, Fdiff and the other functions writing
+// to an io.Writer whether to color their output with ANSI escape codes.
+type ColorMode int32
+
+const (
+	// ColorNever leaves the output uncolored. It is the default.
+	ColorNever ColorMode = iota
+	// ColorAuto colors the output if it goes to a terminal and the
+	// NO_COLOR environment variable is not set.
+	ColorAuto
+	// ColorAlways colors the output wherever it goes.
+	ColorAlways
+)
+
+var colorMode int32
+
+// SetColorMode sets the ColorMode of the package. It is safe to call
+// while other goroutines print.
+func SetColorMode(m ColorMode) {
+	atomic.StoreInt32(&colorMode, int32(m))
+}
+
+// useColor reports whether output to w is colored.
+func useColor(w io.Writer) bool {
+	switch ColorMode(atomic.LoadInt32(&colorMode)) {
+	case ColorAlways:
+		return true
+	case ColorAuto:
+		return os.Getenv("NO_COLOR") == "" && isTerminal(w)
+	}
+	return false
+}
+
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// The escape codes all have the same length, so that tabwriter, which
+// counts them as text, still aligns colored columns.
+const (
+	colorReset  = "\x1b[0m"
+	colorRed    = "\x1b[31m"
+	colorGreen  = "\x1b[32m"
+	colorYellow = "\x1b[33m"
+	colorBlue   = "\x1b[34m"
+	colorPurple = "\x1b[35m"
+	colorCyan   = "\x1b[36m"
+)
+
+const (
+	colorType   = colorCyan
+	colorField  = colorBlue
+	colorString = colorGreen
+	colorNumber = colorPurple
+	colorConst  = colorYellow
+	colorA      = colorRed
+	colorB      = colorGreen
+)
+
+// colorSides colors the a and b sides of a diff format "a != b".
+func colorSides(format string) string {
+	i := strings.Index(format, " != ")
+	if i < 0 {
+		return format
+	}
+	return colorA + format[:i] + colorReset + " != " + colorB + format[i+len(" != "):] + colorReset
+}
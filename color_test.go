@@ -0,0 +1,81 @@
+package pretty
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var ansiEscape = regexp.MustCompile("\x1b\\[[0-9]+m")
+
+func TestColorFormatter(t *testing.T) {
+	type inner struct {
+		Flag bool
+		Ptr  *int
+	}
+	type value struct {
+		Name     string
+		LongName float64
+		Tags     []string
+		Inner    inner
+		Any      interface{}
+		Map      map[string]int
+	}
+	v := value{Name: "n", LongName: 1.5, Tags: []string{"a"}, Inner: inner{Flag: true}, Any: 3, Map: map[string]int{"k": 1, "kk": 2}}
+
+	got := fmt.Sprintf("%# v", ColorFormatter(v))
+	assert.Equal(t, fmt.Sprintf("%# v", Formatter(v)), ansiEscape.ReplaceAllString(got, ""), "colors must not change the layout")
+	assert.Contains(t, got, colorType+"pretty.value"+colorReset+"{")
+	assert.Contains(t, got, colorField+"LongName"+colorReset+":")
+	assert.Contains(t, got, colorString+`"n"`+colorReset)
+	assert.Contains(t, got, colorNumber+"1.5"+colorReset)
+	assert.Contains(t, got, colorConst+"true"+colorReset)
+	assert.Contains(t, got, "("+colorType+"*int"+colorReset+")("+colorConst+"nil"+colorReset+")")
+	assert.Contains(t, got, colorType+"int"+colorReset+"("+colorNumber+"3"+colorReset+")")
+
+	assert.Equal(t, "pretty.value", fmt.Sprintf("%T", ColorFormatter(v).(formatter).v.Interface()))
+	assert.Equal(t, "n", fmt.Sprintf("%v", ColorFormatter("n")))
+}
+
+func TestColorMode(t *testing.T) {
+	defer SetColorMode(ColorNever)
+	a := map[string]int{"x": 1}
+	b := map[string]int{"x": 2}
+
+	var buf bytes.Buffer
+	Fdiff(&buf, a, b)
+	assert.Equal(t, "[\"x\"]: 1 != 2\n", buf.String())
+
+	SetColorMode(ColorAlways)
+	buf.Reset()
+	Fdiff(&buf, a, b)
+	assert.Equal(t, "[\"x\"]: "+colorA+"1"+colorReset+" != "+colorB+"2"+colorReset+"\n", buf.String())
+
+	buf.Reset()
+	Fprintf(&buf, "%v", 1)
+	assert.Equal(t, "1", buf.String())
+	buf.Reset()
+	Fprintf(&buf, "%# v", []int{1})
+	assert.Equal(t, colorType+"[]int"+colorReset+"{"+colorNumber+"1"+colorReset+"}", buf.String())
+	assert.Equal(t, "[]int{1}", Sprint([]int{1}), "strings are never colored")
+
+	SetColorMode(ColorAuto)
+	buf.Reset()
+	Fdiff(&buf, a, b)
+	assert.Equal(t, "[\"x\"]: 1 != 2\n", buf.String(), "a bytes.Buffer is not a terminal")
+
+	f, err := os.CreateTemp(t.TempDir(), "out")
+	assert.NoError(t, err)
+	defer f.Close()
+	assert.False(t, isTerminal(f))
+}
+
+func TestColorSides(t *testing.T) {
+	assert.Equal(t, colorA+"%d"+colorReset+" != "+colorB+"%d"+colorReset, colorSides("%d != %d"))
+	assert.Equal(t, colorA+"nil"+colorReset+" != "+colorB+"%# v"+colorReset, colorSides("nil != %# v"))
+	assert.Equal(t, "no sides", colorSides("no sides"))
+}
@@ -1,8 +1,10 @@
 package pretty
 
 import (
+	"fmt"
 	"math"
 	"reflect"
+	"time"
 )
 
 const defaultPrecision = 0.0000001
@@ -13,6 +15,16 @@ type customDiff struct {
 type Comparator interface {
 	Diff(a, b interface{}) (desc []string, ok bool)
 	StructuredDiff(a, b interface{}) (desc []StructuredDiff, ok bool)
+	// StructuredDiffTo passes the differences StructuredDiff would return
+	// to out as they are found, without collecting them. It reports
+	// whether there were none.
+	StructuredDiffTo(out StructuredDiffer, a, b interface{}) (ok bool)
+	// Equal reports whether Diff would find no differences between a and b.
+	// It stops at the first difference and formats nothing.
+	Equal(a, b interface{}) bool
+	// Patch returns the operations turning a into b, see Apply.
+	// Values the Comparator considers equal are left alone.
+	Patch(a, b interface{}) Patch
 }
 
 type Equals func(a, b interface{}) bool
@@ -20,9 +32,19 @@ type Float64Equals func(a, b float64) bool
 
 type Options struct {
 	customComparators        map[reflect.Type]Equals
-	numericComparator        Float64Equals
+	numericTolerance         *Tolerance
+	precision                float64
 	ignoreTypeNameDifference bool
 	labelFieldNames          []string
+	sliceKeys                map[reflect.Type]sliceKeyFunc
+	unorderedSlices          bool
+	unorderedSliceTypes      map[reflect.Type]struct{}
+	ignorePaths              []pathPattern
+	fieldTolerances          map[string]Tolerance
+	pathTolerances           []pathTolerance
+	exactIntegers            bool
+	timeComparison           timeComparison
+	maxDiffs                 int
 }
 
 func WithIgnoreTypeNameDiffs(ignore bool) func(*Options) {
@@ -31,9 +53,11 @@ func WithIgnoreTypeNameDiffs(ignore bool) func(*Options) {
 	}
 }
 
+// WithPrecision - sets the slack added to absolute numeric tolerances to absorb floating point rounding.
+// It only affects the Comparator built with it.
 func WithPrecision(precision float64) func(*Options) {
 	return func(s *Options) {
-		globalPrecision = precision
+		s.precision = precision
 	}
 }
 
@@ -43,11 +67,9 @@ func WithCustomComparators(customComparators map[reflect.Type]Equals) func(*Opti
 	}
 }
 
-var globalPrecision = defaultPrecision
-
-func newMustAbsoluteDeltaLessThan(e float64) func(a, b float64) bool {
+func newMustAbsoluteDeltaLessThan(e, precision float64) func(a, b float64) bool {
 	return func(a, b float64) bool {
-		return math.Abs(a-b) <= e+globalPrecision
+		return math.Abs(a-b) <= e+precision
 	}
 }
 
@@ -57,61 +79,261 @@ func WithLabelFields(labelFieldNames ...string) func(*Options) {
 	}
 }
 
+// WithSliceKey - matches elements of slices of elemType (or of pointers to it) by the value
+// of the named struct field instead of by position. Unmatched elements are reported as missing.
+// Keys that are or hold slices and maps are compared element by element.
+func WithSliceKey(elemType reflect.Type, fieldName string) func(*Options) {
+	return func(s *Options) {
+		s.sliceKeys[elemType] = fieldKey(fieldName)
+	}
+}
+
+// WithSliceKeyFunc - like WithSliceKey, but the identity of an element is the result of key
+func WithSliceKeyFunc(elemType reflect.Type, key func(elem interface{}) interface{}) func(*Options) {
+	return func(s *Options) {
+		s.sliceKeys[elemType] = funcKey(key)
+	}
+}
+
+// WithUnorderedSlices - compares slices as multisets, pairing equal elements regardless of their order.
+// Without arguments it applies to all slices, otherwise only to the given slice types.
+func WithUnorderedSlices(sliceTypes ...reflect.Type) func(*Options) {
+	return func(s *Options) {
+		if len(sliceTypes) == 0 {
+			s.unorderedSlices = true
+		}
+		for _, t := range sliceTypes {
+			s.unorderedSliceTypes[t] = struct{}{}
+		}
+	}
+}
+
+// WithIgnorePaths - skips differences at paths matching any of the patterns, e.g. "Orders[*].UpdatedAt" or "Meta.*".
+// In a pattern "*" matches any field name, slice index or map key, "[*]" only an index or key and "**" any number
+// of path elements, so "Meta.*" skips the fields of a struct Meta as well as the entries of a map Meta.
+func WithIgnorePaths(patterns ...string) func(*Options) {
+	return func(s *Options) {
+		s.ignorePaths = append(s.ignorePaths, compilePathPatterns(patterns)...)
+	}
+}
+
 // WithNumericEpsilon - sets the maximum tolerance of absolute difference of all numeric types
 func WithNumericEpsilon(epsilon float64) func(*Options) {
 	return func(s *Options) {
-		s.numericComparator = newMustAbsoluteDeltaLessThan(epsilon)
+		s.numericTolerance = &Tolerance{Absolute: epsilon}
+	}
+}
+
+// WithFieldTolerance - compares numbers held by struct fields named fieldName, or by slices, arrays and maps
+// in such fields, with tolerance instead of the global numeric tolerance
+func WithFieldTolerance(fieldName string, tolerance Tolerance) func(*Options) {
+	return func(s *Options) {
+		s.fieldTolerances[fieldName] = tolerance
+	}
+}
+
+// WithPathTolerance - compares numbers at paths matching pattern (see WithIgnorePaths) with tolerance.
+// Path tolerances take precedence over field tolerances and are tried in the order they were given.
+func WithPathTolerance(pattern string, tolerance Tolerance) func(*Options) {
+	return func(s *Options) {
+		s.pathTolerances = append(s.pathTolerances, pathTolerance{compilePathPattern(pattern), tolerance})
+	}
+}
+
+// WithExactIntegers - compares integer types exactly even if a global numeric tolerance is set.
+// Field and path tolerances still apply to integers.
+func WithExactIntegers(exact bool) func(*Options) {
+	return func(s *Options) {
+		s.exactIntegers = exact
+	}
+}
+
+// WithTimeInstants - compares time.Time values as instants with time.Time.Equal, so the same instant
+// in different locations is equal. By default times are compared by their String representation.
+func WithTimeInstants(instants bool) func(*Options) {
+	return func(s *Options) {
+		s.timeComparison.instant = instants
+	}
+}
+
+// WithTimeTolerance - time.Time values are equal if they are at most tolerance apart
+func WithTimeTolerance(tolerance time.Duration) func(*Options) {
+	return func(s *Options) {
+		s.timeComparison.tolerance = tolerance
+	}
+}
+
+// WithTimeTruncate - truncates time.Time values to a multiple of granularity (see time.Time.Truncate)
+// before comparing them as instants
+func WithTimeTruncate(granularity time.Duration) func(*Options) {
+	return func(s *Options) {
+		s.timeComparison.truncate = granularity
+	}
+}
+
+// WithIgnoreTimeLocation - compares the wall clock readings of time.Time values regardless of
+// their locations, so 10:00 UTC equals 10:00 CET
+func WithIgnoreTimeLocation(ignore bool) func(*Options) {
+	return func(s *Options) {
+		s.timeComparison.ignoreLocation = ignore
+	}
+}
+
+// WithMaxDiffs - stops the walk after n differences. Diff and StructuredDiff then end with a marker
+// telling how many further differences and values were left out. n <= 0 means no limit.
+func WithMaxDiffs(n int) func(*Options) {
+	return func(s *Options) {
+		s.maxDiffs = n
+	}
+}
+
+// WithRelativeEpsilon - sets the maximum tolerance of relative difference |a-b| <= r*max(|a|,|b|) of all numeric types
+func WithRelativeEpsilon(r float64) func(*Options) {
+	return func(s *Options) {
+		s.numericTolerance = &Tolerance{Relative: r}
+	}
+}
+
+// WithAbsoluteOrRelativeEpsilon - numbers are equal if either their absolute difference is within epsilon
+// or their relative difference is within r. The absolute part covers values close to zero.
+func WithAbsoluteOrRelativeEpsilon(epsilon, r float64) func(*Options) {
+	return func(s *Options) {
+		s.numericTolerance = &Tolerance{Absolute: epsilon, Relative: r}
 	}
 }
 
+// WithMaxULPs - numbers are equal if at most ulps representable float64 values lie between them
+func WithMaxULPs(ulps uint64) func(*Options) {
+	return func(s *Options) {
+		s.numericTolerance = &Tolerance{ULPs: ulps}
+	}
+}
+
+// NewCustomDiff returns a Comparator configured by options. The Comparator
+// is immutable and safe for concurrent use.
 func NewCustomDiff(options ...func(*Options)) Comparator {
 	opts := Options{
-		customComparators: make(map[reflect.Type]Equals),
+		customComparators:   make(map[reflect.Type]Equals),
+		precision:           defaultPrecision,
+		sliceKeys:           make(map[reflect.Type]sliceKeyFunc),
+		unorderedSliceTypes: make(map[reflect.Type]struct{}),
+		fieldTolerances:     make(map[string]Tolerance),
 	}
 
 	for _, o := range options {
 		o(&opts)
 	}
-	return &customDiffPrinter{
-		customComparators:        opts.customComparators,
-		numericComparator:        opts.numericComparator,
+
+	config := &diffConfig{
+		customComparators:        make(map[reflect.Type]Equals, len(opts.customComparators)),
+		precision:                opts.precision,
 		ignoreTypeNameDifference: opts.ignoreTypeNameDifference,
-		labelNames:               opts.labelFieldNames,
+		labelNames:               append([]string(nil), opts.labelFieldNames...),
+		sliceKeys:                opts.sliceKeys,
+		unorderedSlices:          opts.unorderedSlices,
+		unorderedSliceTypes:      opts.unorderedSliceTypes,
+		ignorePaths:              opts.ignorePaths,
+		fieldNumeric:             make(map[string]Float64Equals, len(opts.fieldTolerances)),
+		exactIntegers:            opts.exactIntegers,
+		timeEquals:               opts.timeComparison.comparator(),
+		maxDiffs:                 opts.maxDiffs,
+	}
+	// The caller may keep modifying the map passed to WithCustomComparators.
+	for t, equals := range opts.customComparators {
+		config.customComparators[t] = equals
+	}
+	if opts.numericTolerance != nil {
+		config.numericComparator = opts.numericTolerance.comparator(opts.precision)
+	}
+	for name, tolerance := range opts.fieldTolerances {
+		config.fieldNumeric[name] = tolerance.comparator(opts.precision)
 	}
+	for _, r := range opts.pathTolerances {
+		config.pathNumeric = append(config.pathNumeric, numericRule{r.pattern, r.tolerance.comparator(opts.precision)})
+	}
+	return &customDiffPrinter{config: config}
 }
 
 type customDiffPrinter struct {
-	customComparators        map[reflect.Type]Equals
-	numericComparator        Float64Equals
-	ignoreTypeNameDifference bool
-	labelNames               []string
+	config *diffConfig
+}
+
+// printer returns a diffPrinter writing to w with the options of c.
+func (c customDiffPrinter) printer(w Printfer) diffPrinter {
+	p := diffPrinter{
+		diffConfig: c.config,
+		w:          w,
+		labels:     NewLabels(c.config.labelNames...),
+		aVisited:   make(map[visit]visit),
+		bVisited:   make(map[visit]visit),
+	}
+	if c.config.maxDiffs > 0 {
+		p.budget = &diffBudget{max: c.config.maxDiffs}
+	}
+	return p
+}
+
+// truncatedMarker describes what a walk that ran out of budget left out.
+func truncatedMarker(b *diffBudget) string {
+	return fmt.Sprintf("stopped after %d differences, %d more differences or uncompared values", b.reported, b.skipped)
 }
 
 func (c customDiffPrinter) Diff(a, b interface{}) (desc []string, ok bool) {
-	diffPrinter{
-		w:                        (*sbuf)(&desc),
-		ignoreTypeNameDifference: c.ignoreTypeNameDifference,
-		customComparators:        c.customComparators,
-		numericComparator:        c.numericComparator,
-		labels:                   NewLabels(c.labelNames...),
-		aVisited:                 make(map[visit]visit),
-		bVisited:                 make(map[visit]visit),
-	}.diff(reflect.ValueOf(a), reflect.ValueOf(b))
+	p := c.printer((*sbuf)(&desc))
+	p.diff(reflect.ValueOf(a), reflect.ValueOf(b))
+	if p.budget != nil && p.budget.skipped > 0 {
+		desc = append(desc, "...: "+truncatedMarker(p.budget))
+	}
 	return desc, len(desc) == 0
 }
 
 func (c customDiffPrinter) StructuredDiff(a, b interface{}) (desc []StructuredDiff, ok bool) {
-	descStr := make([]string, 0)
 	structuredOut := NewStructuredDiffer()
-	diffPrinter{
-		w:                        (*sbuf)(&descStr),
-		structuredOutput:         structuredOut,
-		ignoreTypeNameDifference: c.ignoreTypeNameDifference,
-		customComparators:        c.customComparators,
-		numericComparator:        c.numericComparator,
-		labels:                   NewLabels(c.labelNames...),
-		aVisited:                 make(map[visit]visit),
-		bVisited:                 make(map[visit]visit),
-	}.diff(reflect.ValueOf(a), reflect.ValueOf(b))
-	return structuredOut.Results(), len(structuredOut.Results()) == 0
+	ok = c.StructuredDiffTo(structuredOut, a, b)
+	return structuredOut.Results(), ok
+}
+
+func (c customDiffPrinter) StructuredDiffTo(out StructuredDiffer, a, b interface{}) (ok bool) {
+	var count countPrintfer
+	p := c.printer(&count)
+	p.structuredOutput = out
+	// Register the tagged labels up front, so that all differences have
+	// the same labels, not only those found after the tagged fields.
+	if r, ok := p.labels.(labelRegisterer); ok {
+		for _, name := range taggedLabels(reflect.TypeOf(a)) {
+			r.register(name)
+		}
+		for _, name := range taggedLabels(reflect.TypeOf(b)) {
+			r.register(name)
+		}
+	}
+	p.diff(reflect.ValueOf(a), reflect.ValueOf(b))
+	if p.budget != nil && p.budget.skipped > 0 {
+		out.Print(StructuredDiff{
+			FieldName: "...",
+			Labels:    []Label{},
+			ValueA:    "(truncated)",
+			ValueB:    truncatedMarker(p.budget),
+			Kind:      Truncated,
+		})
+	}
+	return count == 0
+}
+
+// countPrintfer counts the differences printed to it without formatting them.
+type countPrintfer int
+
+func (c *countPrintfer) Printf(format string, a ...interface{}) {
+	*c++
+}
+
+func (c customDiffPrinter) Equal(a, b interface{}) bool {
+	return diffPrinter{diffConfig: c.config}.equal(reflect.ValueOf(a), reflect.ValueOf(b))
+}
+
+func (c customDiffPrinter) Patch(a, b interface{}) Patch {
+	var ops Patch
+	p := c.printer(nil)
+	p.patch(&ops, nil, reflect.ValueOf(a), reflect.ValueOf(b))
+	return ops
 }
@@ -102,6 +102,8 @@ func Test_customStructuredDiffPrinter_Diff(t *testing.T) {
 					Labels:    []Label{},
 					ValueA:    "1",
 					ValueB:    "2",
+					Path:      Path{fieldStep("intField")},
+					Numeric:   &NumericDelta{A: 1, B: 2, Absolute: 1, Relative: 0.5},
 				},
 			},
 			wantOk: false,
@@ -213,6 +215,7 @@ func Test_customStructuredDiffPrinter_Diff(t *testing.T) {
 					Labels:    []Label{{Name: "str", Value: "strValue A"}},
 					ValueA:    "\"strValue A\"",
 					ValueB:    "\"strValue B\"",
+					Path:      Path{fieldStep("child"), fieldStep("str")},
 				},
 			},
 			wantOk: false,
@@ -245,6 +248,7 @@ func Test_customStructuredDiffPrinter_Diff(t *testing.T) {
 					Labels:    []Label{{Name: "str", Value: "strValue A"}},
 					ValueA:    "\"strValue A\"",
 					ValueB:    "\"strValue B\"",
+					Path:      Path{fieldStep("child"), fieldStep("str")},
 				},
 			},
 			wantOk: false,
@@ -272,12 +276,22 @@ func Test_customStructuredDiffPrinter_Diff(t *testing.T) {
 					Labels:    []Label{},
 					ValueA:    "2022-07-01 01:31:31 +0200 CEST",
 					ValueB:    "2022-07-02 01:31:31 +0200 CEST",
+					A:         time.Date(2022, time.July, 1, 1, 31, 31, 0, time.Local),
+					B:         time.Date(2022, time.July, 2, 1, 31, 31, 0, time.Local),
+					Path:      Path{fieldStep("TimeField")},
 				},
 				{
 					FieldName: "timeField.ext",
 					Labels:    []Label{},
 					ValueA:    "63792228691",
 					ValueB:    "63792315091",
+					Path:      Path{fieldStep("timeField"), fieldStep("ext")},
+					Numeric: &NumericDelta{
+						A:        63792228691,
+						B:        63792315091,
+						Absolute: 86400,
+						Relative: 86400.0 / 63792315091,
+					},
 				},
 			},
 			wantOk: false,
@@ -296,3 +310,145 @@ func Test_customStructuredDiffPrinter_Diff(t *testing.T) {
 		})
 	}
 }
+
+func Test_customStructuredDiffPrinter_Kinds(t *testing.T) {
+	type node struct {
+		Name string
+		Next *node
+	}
+	type value struct {
+		Ptr   *int
+		Any   interface{}
+		Map   map[string]int
+		Slice []int
+		Node  *node
+	}
+	one := 1
+	loop := &node{Name: "loop"}
+	loop.Next = loop
+	a := value{Ptr: &one, Any: 1, Map: map[string]int{"a": 1}, Slice: []int{1, 2}, Node: loop}
+	b := value{Any: "1", Map: map[string]int{"b": 2}, Slice: []int{2}, Node: &node{Name: "loop", Next: &node{Name: "loop"}}}
+
+	got, ok := NewCustomDiff().StructuredDiff(a, b)
+	assert.False(t, ok)
+	type kindOf struct {
+		FieldName string
+		Kind      DiffKind
+		A, B      interface{}
+	}
+	var kinds []kindOf
+	for _, d := range got {
+		kinds = append(kinds, kindOf{d.FieldName, d.Kind, d.A, d.B})
+	}
+	assert.Equal(t, []kindOf{
+		{"Ptr", NilMismatch, &one, (*int)(nil)},
+		{"Any", TypeMismatch, 1, "1"},
+		{"Map[\"a\"]", Removed, 1, nil},
+		{"Map[\"b\"]", Added, nil, 2},
+		{"Slice[0]", Removed, 1, nil},
+		{"Node.Next", Cycle, *loop, *b.Node.Next},
+	}, kinds)
+	assert.Equal(t, "nil mismatch", NilMismatch.String())
+}
+
+func Test_customStructuredDiffPrinter_Path(t *testing.T) {
+	type item struct {
+		ID    string `pretty:"label"`
+		Value int
+	}
+	type value struct {
+		Items map[string]item
+		More  map[string]item
+		Any   interface{}
+	}
+	a := value{
+		Items: map[string]item{"a.b": {"1", 1}},
+		More:  map[string]item{"c[0]": {"2", 1}},
+		Any:   &item{"3", 1},
+	}
+	b := value{
+		Items: map[string]item{"a.b": {"1", 2}},
+		More:  map[string]item{"c[0]": {"2", 2}},
+		Any:   &item{"3", 2},
+	}
+
+	got, ok := NewCustomDiff().StructuredDiff(a, b)
+	assert.False(t, ok)
+	type pathOf struct {
+		FieldName string
+		Path      Path
+		Labels    []Label
+	}
+	var paths []pathOf
+	for _, d := range got {
+		paths = append(paths, pathOf{d.FieldName, d.Path, d.Labels})
+	}
+	assert.Equal(t, []pathOf{
+		{`Items["a.b"].Value`, Path{fieldStep("Items"), keyStep("a.b"), fieldStep("Value")}, []Label{{Name: "ID", Value: "1"}}},
+		{`More["c[0]"].Value`, Path{fieldStep("More"), keyStep("c[0]"), fieldStep("Value")}, []Label{{Name: "ID", Value: "2"}}},
+		{"Any.Value", Path{fieldStep("Any"), unwrapStep, derefStep, fieldStep("Value")}, []Label{{Name: "ID", Value: "3"}}},
+	}, paths)
+	for _, p := range paths {
+		assert.Equal(t, p.FieldName, p.Path.String())
+	}
+}
+
+func Test_customStructuredDiffPrinter_StructuredDiffTo(t *testing.T) {
+	a := make([]int, 10)
+	b := make([]int, 10)
+	for i := range b {
+		b[i] = i % 3
+	}
+	c := NewCustomDiff(WithMaxDiffs(4))
+	want, _ := c.StructuredDiff(a, b)
+
+	diffs := make(chan StructuredDiff, len(want))
+	ok := c.StructuredDiffTo(StructuredDifferFunc(func(d StructuredDiff) {
+		diffs <- d
+	}), a, b)
+	close(diffs)
+	assert.False(t, ok)
+	var got []StructuredDiff
+	for d := range diffs {
+		got = append(got, d)
+	}
+	assert.Equal(t, want, got)
+	assert.Equal(t, Truncated, got[len(got)-1].Kind)
+
+	out := NewStructuredDiffer()
+	assert.True(t, c.StructuredDiffTo(out, a, a))
+	assert.Empty(t, out.Results())
+}
+
+func Test_customStructuredDiffPrinter_UnexportedValues(t *testing.T) {
+	type value struct {
+		Exported *int
+		hidden   *int
+		tags     map[string]int
+	}
+	one := 1
+	a := value{Exported: &one, hidden: &one, tags: map[string]int{"a": 1}}
+	b := value{Exported: nil, hidden: nil, tags: map[string]int{"a": 2}}
+
+	got, ok := NewCustomDiff().StructuredDiff(a, b)
+	assert.False(t, ok)
+	assert.Len(t, got, 3)
+	assert.Equal(t, &one, got[0].A, "exported values are passed on")
+	for _, d := range got[1:] {
+		assert.Nil(t, d.A, d.FieldName)
+		assert.Nil(t, d.B, d.FieldName)
+	}
+}
+
+func Test_customStructuredDiffPrinter_LengthMismatch(t *testing.T) {
+	c := NewCustomDiff(WithIgnoreTypeNameDiffs(true))
+	got, ok := c.StructuredDiff([3]int{1, 2, 3}, [2]int{1, 2})
+	assert.False(t, ok)
+	assert.Equal(t, []StructuredDiff{
+		{Labels: []Label{}, ValueA: "[1 2 3]", ValueB: "[1 2]", Kind: LengthMismatch, A: [3]int{1, 2, 3}, B: [2]int{1, 2}},
+	}, got)
+
+	diffs, ok := c.Diff(struct{ A [1]string }{[1]string{"x"}}, struct{ A [2]string }{})
+	assert.False(t, ok)
+	assert.Equal(t, []string{`A: [1]string{"x"} != [2]string{"", ""}`}, diffs)
+}
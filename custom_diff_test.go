@@ -1,7 +1,13 @@
 package pretty
 
 import (
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -145,3 +151,526 @@ func Test_customDiffPrinter_Diff(t *testing.T) {
 		})
 	}
 }
+
+func Test_customDiffPrinter_SliceKey(t *testing.T) {
+	type order struct {
+		ID    int
+		Price float64
+	}
+	type keyed struct {
+		Key   []string
+		Price float64
+	}
+	a := []order{{ID: 1, Price: 10}, {ID: 2, Price: 20}, {ID: 3, Price: 30}}
+	b := []order{{ID: 4, Price: 40}, {ID: 3, Price: 30}, {ID: 1, Price: 11}}
+
+	tests := []struct {
+		name     string
+		opts     []func(*Options)
+		a, b     interface{}
+		wantDesc []string
+	}{
+		{
+			name:     "by field",
+			opts:     []func(*Options){WithSliceKey(reflect.TypeOf(order{}), "ID")},
+			a:        a,
+			b:        b,
+			wantDesc: []string{"[0].Price: 10 != 11", "[1]: pretty.order{ID:2, Price:20} != (missing)", "[0]: (missing) != pretty.order{ID:4, Price:40}"},
+		},
+		{
+			name:     "pointer elements",
+			opts:     []func(*Options){WithSliceKey(reflect.TypeOf(order{}), "ID")},
+			a:        []*order{&a[0], &a[2]},
+			b:        []*order{&b[1], &b[2]},
+			wantDesc: []string{"[0].Price: 10 != 11"},
+		},
+		{
+			name: "by func",
+			opts: []func(*Options){WithSliceKeyFunc(reflect.TypeOf(order{}), func(elem interface{}) interface{} {
+				return elem.(order).ID
+			})},
+			a:        a[:1],
+			b:        b[2:],
+			wantDesc: []string{"[0].Price: 10 != 11"},
+		},
+		{
+			name:     "reorder only",
+			opts:     []func(*Options){WithSliceKey(reflect.TypeOf(order{}), "ID")},
+			a:        a,
+			b:        []order{a[2], a[0], a[1]},
+			wantDesc: nil,
+		},
+		{
+			name:     "slice keys",
+			opts:     []func(*Options){WithSliceKey(reflect.TypeOf(keyed{}), "Key")},
+			a:        []keyed{{Key: []string{"a", "b"}, Price: 1}, {Key: []string{"c"}, Price: 2}},
+			b:        []keyed{{Key: []string{"c"}, Price: 3}, {Key: []string{"a"}, Price: 1}},
+			wantDesc: []string{"[0]: pretty.keyed{\n    Key:   {\"a\", \"b\"},\n    Price: 1,\n} != (missing)", "[1].Price: 2 != 3", "[1]: (missing) != pretty.keyed{\n    Key:   {\"a\"},\n    Price: 1,\n}"},
+		},
+		{
+			name: "keys holding slices in interfaces",
+			opts: []func(*Options){WithSliceKeyFunc(reflect.TypeOf(order{}), func(elem interface{}) interface{} {
+				return struct{ V interface{} }{[]int{elem.(order).ID}}
+			})},
+			a:        a,
+			b:        b,
+			wantDesc: []string{"[0].Price: 10 != 11", "[1]: pretty.order{ID:2, Price:20} != (missing)", "[0]: (missing) != pretty.order{ID:4, Price:40}"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotDesc, gotOk := NewCustomDiff(tt.opts...).Diff(tt.a, tt.b)
+			assert.Equal(t, tt.wantDesc, gotDesc)
+			assert.Equal(t, len(tt.wantDesc) == 0, gotOk)
+		})
+	}
+}
+
+func Test_customDiffPrinter_UnorderedSlices(t *testing.T) {
+	type item struct {
+		Name  string
+		Price float64
+	}
+	sorted := make([]int, 3000)
+	for i := range sorted {
+		sorted[i] = i
+	}
+	shuffled := append([]int(nil), sorted...)
+	rand.New(rand.NewSource(1)).Shuffle(len(shuffled), func(i, j int) {
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	})
+	tests := []struct {
+		name     string
+		opts     []func(*Options)
+		a, b     interface{}
+		wantDesc []string
+	}{
+		{
+			name:     "all slices",
+			opts:     []func(*Options){WithUnorderedSlices()},
+			a:        []int{1, 2, 2, 3},
+			b:        []int{3, 2, 1, 2},
+			wantDesc: nil,
+		},
+		{
+			name:     "leftovers",
+			opts:     []func(*Options){WithUnorderedSlices()},
+			a:        []int{1, 2, 2, 3},
+			b:        []int{4, 2, 1},
+			wantDesc: []string{"[2]: int(2) != (missing)", "[3]: int(3) != (missing)", "[0]: (missing) != int(4)"},
+		},
+		{
+			name:     "shuffled",
+			opts:     []func(*Options){WithUnorderedSlices()},
+			a:        sorted,
+			b:        shuffled,
+			wantDesc: nil,
+		},
+		{
+			name:     "NaN",
+			opts:     []func(*Options){WithUnorderedSlices()},
+			a:        []float64{math.NaN(), 1},
+			b:        []float64{1, math.NaN()},
+			wantDesc: []string{"[0]: float64(NaN) != (missing)", "[1]: (missing) != float64(NaN)"},
+		},
+		{
+			name:     "with epsilon",
+			opts:     []func(*Options){WithUnorderedSlices(reflect.TypeOf([]item{})), WithNumericEpsilon(0.01)},
+			a:        []item{{"a", 1}, {"b", 2}},
+			b:        []item{{"b", 2.001}, {"a", 0.999}},
+			wantDesc: nil,
+		},
+		{
+			name:     "epsilon needs re-pairing",
+			opts:     []func(*Options){WithUnorderedSlices(), WithNumericEpsilon(0.01)},
+			a:        []float64{1.00, 1.02},
+			b:        []float64{1.01, 0.99},
+			wantDesc: nil,
+		},
+		{
+			name:     "other types stay ordered",
+			opts:     []func(*Options){WithUnorderedSlices(reflect.TypeOf([]item{}))},
+			a:        []int{1, 2},
+			b:        []int{2, 1},
+			wantDesc: []string{"[0]: 1 != 2", "[1]: 2 != 1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotDesc, gotOk := NewCustomDiff(tt.opts...).Diff(tt.a, tt.b)
+			assert.Equal(t, tt.wantDesc, gotDesc)
+			assert.Equal(t, len(tt.wantDesc) == 0, gotOk)
+		})
+	}
+}
+
+func Test_customDiffPrinter_IgnorePaths(t *testing.T) {
+	type order struct {
+		ID        int
+		UpdatedAt int64
+	}
+	type batch struct {
+		Orders []order
+		Meta   map[string]string
+		Name   string
+	}
+	a := batch{
+		Orders: []order{{ID: 1, UpdatedAt: 100}, {ID: 2, UpdatedAt: 100}},
+		Meta:   map[string]string{"host": "a"},
+		Name:   "x",
+	}
+	b := batch{
+		Orders: []order{{ID: 1, UpdatedAt: 200}, {ID: 3, UpdatedAt: 200}, {ID: 2, UpdatedAt: 300}},
+		Meta:   map[string]string{"host": "b"},
+		Name:   "y",
+	}
+	gotDesc, gotOk := NewCustomDiff(WithIgnorePaths("Orders[*].UpdatedAt", "Meta.*")).Diff(a, b)
+	assert.False(t, gotOk)
+	assert.Equal(t, []string{
+		"Orders[1]: (missing) != pretty.order{ID:3, UpdatedAt:200}",
+		`Name: "x" != "y"`,
+	}, gotDesc)
+
+	type lists struct {
+		M map[string]int
+		L []int
+	}
+	gotDesc, gotOk = NewCustomDiff(WithIgnorePaths("M[*]", "L[*]")).Diff(
+		lists{M: map[string]int{"a": 1}, L: []int{1, 2, 3}},
+		lists{M: map[string]int{"b": 2}, L: []int{1, 3, 4}},
+	)
+	assert.True(t, gotOk)
+	assert.Empty(t, gotDesc)
+}
+
+func Test_customDiffPrinter_NumericTolerances(t *testing.T) {
+	type measurement struct {
+		Tiny float64
+		Huge float64
+	}
+	a := measurement{Tiny: 1e-9, Huge: 1e12}
+	b := measurement{Tiny: 1.0001e-9, Huge: 1.0001e12}
+
+	tests := []struct {
+		name     string
+		opts     []func(*Options)
+		wantDesc []string
+	}{
+		{
+			name:     "absolute",
+			opts:     []func(*Options){WithNumericEpsilon(1e-6)},
+			wantDesc: []string{"Huge: 1e+12 != 1.0001e+12"},
+		},
+		{
+			name: "relative",
+			opts: []func(*Options){WithRelativeEpsilon(1e-3)},
+		},
+		{
+			name:     "relative too strict",
+			opts:     []func(*Options){WithRelativeEpsilon(1e-5)},
+			wantDesc: []string{"Tiny: 1e-09 != 1.0001e-09", "Huge: 1e+12 != 1.0001e+12"},
+		},
+		{
+			name: "absolute or relative",
+			opts: []func(*Options){WithAbsoluteOrRelativeEpsilon(1e-6, 1e-3)},
+		},
+		{
+			name:     "ulps",
+			opts:     []func(*Options){WithMaxULPs(4)},
+			wantDesc: []string{"Tiny: 1e-09 != 1.0001e-09", "Huge: 1e+12 != 1.0001e+12"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotDesc, gotOk := NewCustomDiff(tt.opts...).Diff(a, b)
+			assert.Equal(t, tt.wantDesc, gotDesc)
+			assert.Equal(t, len(tt.wantDesc) == 0, gotOk)
+		})
+	}
+}
+
+func Test_customDiffPrinter_FieldAndPathTolerances(t *testing.T) {
+	type line struct {
+		Price  float64
+		Weight float64
+		Count  int
+	}
+	type order struct {
+		Lines  []line
+		Prices map[string]float64
+		Total  float64
+	}
+	a := order{
+		Lines:  []line{{Price: 1, Weight: 1, Count: 10}},
+		Prices: map[string]float64{"x": 1},
+		Total:  1,
+	}
+	b := order{
+		Lines:  []line{{Price: 1.005, Weight: 1.0001, Count: 11}},
+		Prices: map[string]float64{"x": 1.005},
+		Total:  1.005,
+	}
+
+	tests := []struct {
+		name     string
+		opts     []func(*Options)
+		wantDesc []string
+	}{
+		{
+			name: "global epsilon hides counts",
+			opts: []func(*Options){WithNumericEpsilon(1)},
+		},
+		{
+			name:     "exact integers",
+			opts:     []func(*Options){WithNumericEpsilon(1), WithExactIntegers(true)},
+			wantDesc: []string{"Lines[0].Count: 10 != 11"},
+		},
+		{
+			name: "field tolerances",
+			opts: []func(*Options){
+				WithFieldTolerance("Price", Tolerance{Absolute: 0.01}),
+				WithFieldTolerance("Prices", Tolerance{Absolute: 0.01}),
+				WithFieldTolerance("Weight", Tolerance{Absolute: 1e-6}),
+			},
+			wantDesc: []string{"Lines[0].Weight: 1 != 1.0001", "Lines[0].Count: 10 != 11", "Total: 1 != 1.005"},
+		},
+		{
+			name: "path before field",
+			opts: []func(*Options){
+				WithNumericEpsilon(0.01),
+				WithFieldTolerance("Weight", Tolerance{Relative: 1e-3}),
+				WithPathTolerance("Lines[*].Weight", Tolerance{}),
+				WithPathTolerance("Lines[*].Count", Tolerance{Absolute: 1}),
+			},
+			wantDesc: []string{"Lines[0].Weight: 1 != 1.0001"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotDesc, gotOk := NewCustomDiff(tt.opts...).Diff(a, b)
+			assert.Equal(t, tt.wantDesc, gotDesc)
+			assert.Equal(t, len(tt.wantDesc) == 0, gotOk)
+		})
+	}
+}
+
+func Test_customDiffPrinter_PrecisionIsPerComparator(t *testing.T) {
+	strict := NewCustomDiff(WithNumericEpsilon(0), WithPrecision(0))
+	loose := NewCustomDiff(WithNumericEpsilon(0), WithPrecision(0.5))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			c := NewCustomDiff(WithNumericEpsilon(0), WithPrecision(0))
+			for j := 0; j < 100; j++ {
+				if _, ok := c.Diff(1.0, 1.25); ok {
+					t.Error("strict comparator built in parallel reports 1 == 1.25")
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			c := NewCustomDiff(WithNumericEpsilon(0), WithPrecision(0.5))
+			for j := 0; j < 100; j++ {
+				if desc, ok := c.Diff(1.0, 1.25); !ok {
+					t.Errorf("loose comparator built in parallel reports %v", desc)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	_, ok := strict.Diff(1.0, 1.25)
+	assert.False(t, ok)
+	_, ok = loose.Diff(1.0, 1.25)
+	assert.True(t, ok)
+}
+
+func Test_customDiffPrinter_OptionsAreCopied(t *testing.T) {
+	type money int
+	comparators := map[reflect.Type]Equals{
+		reflect.TypeOf(money(0)): func(a, b interface{}) bool { return true },
+	}
+	c := NewCustomDiff(WithCustomComparators(comparators))
+	delete(comparators, reflect.TypeOf(money(0)))
+
+	_, ok := c.Diff(money(1), money(2))
+	assert.True(t, ok)
+}
+
+type benchRecord struct {
+	ID      string
+	Price   float64
+	Count   int
+	Tags    []string
+	Created time.Time
+	Attrs   map[string]int
+	Nested  struct{ A, B float64 }
+}
+
+func BenchmarkComparatorDiff(b *testing.B) {
+	records := make([]benchRecord, 1000)
+	for i := range records {
+		records[i] = benchRecord{
+			ID:      "id",
+			Price:   float64(i),
+			Count:   i,
+			Tags:    []string{"a", "b"},
+			Created: time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+			Attrs:   map[string]int{"x": i},
+		}
+	}
+	other := append([]benchRecord(nil), records...)
+	c := NewCustomDiff(WithNumericEpsilon(0.01), WithLabelFields("ID"))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.Diff(records, other)
+	}
+}
+
+// noFormat counts its formatting in noFormatted, as fmt recovers the
+// panic.
+type noFormat int
+
+var noFormatted int
+
+func (noFormat) Format(f fmt.State, c rune) {
+	noFormatted++
+	panic("noFormat must not be formatted")
+}
+
+func (noFormat) String() string {
+	noFormatted++
+	panic("noFormat must not be formatted")
+}
+
+func Test_customDiffPrinter_Equal(t *testing.T) {
+	type item struct {
+		N     noFormat
+		Price float64
+		Tags  []noFormat
+		ByKey map[noFormat]int
+	}
+	tests := []struct {
+		name string
+		opts []func(*Options)
+		a, b interface{}
+		want bool
+	}{
+		{name: "nil", a: nil, b: nil, want: true},
+		{name: "equal", a: item{N: 1, Price: 2}, b: item{N: 1, Price: 2}, want: true},
+		{name: "differs", a: item{N: 1}, b: item{N: 2}, want: false},
+		{name: "slice length", a: item{Tags: []noFormat{1}}, b: item{Tags: []noFormat{1, 2}}, want: false},
+		{name: "map keys", a: item{ByKey: map[noFormat]int{1: 1, 2: 2}}, b: item{ByKey: map[noFormat]int{1: 1, 2: 2}}, want: true},
+		{name: "map values differ", a: item{ByKey: map[noFormat]int{1: 1, 2: 2}}, b: item{ByKey: map[noFormat]int{1: 1, 2: 3}}, want: false},
+		{name: "epsilon", opts: []func(*Options){WithNumericEpsilon(0.1)}, a: item{Price: 1}, b: item{Price: 1.05}, want: true},
+		{name: "ignored", opts: []func(*Options){WithIgnorePaths("N")}, a: item{N: 1}, b: item{N: 2}, want: true},
+		{name: "type names", opts: []func(*Options){WithIgnoreTypeNameDiffs(true)}, a: noFormat(1), b: 1, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewCustomDiff(tt.opts...)
+			noFormatted = 0
+			assert.Equal(t, tt.want, c.Equal(tt.a, tt.b))
+			assert.Zero(t, noFormatted, "Equal formatted a value")
+			if tt.want {
+				_, ok := c.Diff(tt.a, tt.b)
+				assert.True(t, ok)
+			}
+		})
+	}
+}
+
+func BenchmarkComparatorEqual(b *testing.B) {
+	records := make([]benchRecord, 1000)
+	other := make([]benchRecord, 1000)
+	for i := range other {
+		other[i].Price = 1
+	}
+	c := NewCustomDiff(WithNumericEpsilon(0.01))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.Equal(records, other)
+	}
+}
+
+func Test_customDiffPrinter_MaxDiffs(t *testing.T) {
+	a := make([]int, 100)
+	b := make([]int, 100)
+	for i := range b {
+		b[i] = i + 1
+	}
+	c := NewCustomDiff(WithMaxDiffs(2))
+
+	desc, ok := c.Diff(a, b)
+	assert.False(t, ok)
+	assert.Equal(t, []string{
+		"[0]: 0 != 1",
+		"[1]: 0 != 2",
+		"...: stopped after 2 differences, 98 more differences or uncompared values",
+	}, desc)
+
+	structured, ok := c.StructuredDiff(a, b)
+	assert.False(t, ok)
+	assert.Equal(t, []StructuredDiff{
+		{FieldName: "[0]", Labels: []Label{}, ValueA: "0", ValueB: "1", A: 0, B: 1, Path: Path{indexStep(0)}, Numeric: &NumericDelta{A: 0, B: 1, Absolute: 1, Relative: 1}},
+		{FieldName: "[1]", Labels: []Label{}, ValueA: "0", ValueB: "2", A: 0, B: 2, Path: Path{indexStep(1)}, Numeric: &NumericDelta{A: 0, B: 2, Absolute: 2, Relative: 1}},
+		{FieldName: "...", Labels: []Label{}, ValueA: "(truncated)", ValueB: "stopped after 2 differences, 98 more differences or uncompared values", Kind: Truncated},
+	}, structured)
+
+	desc, ok = NewCustomDiff(WithMaxDiffs(100)).Diff(a, b)
+	assert.False(t, ok)
+	assert.Len(t, desc, 100)
+
+	desc, ok = c.Diff(a[:2], b[:2])
+	assert.False(t, ok)
+	assert.Len(t, desc, 2, "no marker when nothing was left out")
+
+	assert.False(t, c.Equal(a, b))
+}
+
+func Test_customDiffPrinter_MaxDiffsStopsSliceMatching(t *testing.T) {
+	type token int
+	var calls int
+	countingEquals := map[reflect.Type]Equals{reflect.TypeOf(token(0)): func(a, b interface{}) bool {
+		calls++
+		return a == b
+	}}
+	a := make([]token, 300)
+	b := make([]token, 300)
+	for i := range a {
+		a[i], b[i] = token(i), token(i+1000)
+	}
+
+	desc, ok := NewCustomDiff(WithUnorderedSlices(), WithCustomComparators(countingEquals), WithMaxDiffs(1)).Diff(a, b)
+	assert.False(t, ok)
+	assert.Equal(t, []string{
+		"[0]: pretty.token(0) != (missing)",
+		"...: stopped after 1 differences, 599 more differences or uncompared values",
+	}, desc)
+	assert.Equal(t, len(b), calls, "only the first element is matched")
+
+	calls = 0
+	desc, ok = NewCustomDiff(WithCustomComparators(countingEquals), WithMaxDiffs(1)).Diff(a, append(b, 0))
+	assert.False(t, ok)
+	assert.Equal(t, []string{
+		"[0]: 0 != 1000",
+		"...: stopped after 1 differences, 300 more differences or uncompared values",
+	}, desc)
+	assert.Less(t, calls, 50, "the edit script is given up within the budget")
+
+	type record struct {
+		ID    int
+		Value token
+	}
+	ra := []record{{1, 1}, {2, 2}, {3, 3}}
+	rb := []record{{3, 0}, {2, 0}, {1, 0}, {4, 0}}
+	desc, ok = NewCustomDiff(WithSliceKey(reflect.TypeOf(record{}), "ID"), WithMaxDiffs(1)).Diff(ra, rb)
+	assert.False(t, ok)
+	assert.Equal(t, []string{
+		"[0].Value: 1 != 0",
+		"...: stopped after 1 differences, 5 more differences or uncompared values",
+	}, desc)
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io"
 	"reflect"
+	"strconv"
 	"time"
 	"unsafe"
 )
@@ -31,8 +32,10 @@ func (p *wprintfer) Printf(format string, a ...interface{}) {
 }
 
 // Fdiff writes to w a description of the differences between a and b.
+// The a and b sides of each difference are colored red and green if
+// the ColorMode allows it for w, see SetColorMode.
 func Fdiff(w io.Writer, a, b interface{}) {
-	Pdiff(&wprintfer{w}, a, b)
+	pdiff(&wprintfer{w}, useColor(w), a, b)
 }
 
 type Printfer interface {
@@ -43,11 +46,17 @@ type Printfer interface {
 // It calls Printf once for each difference, with no trailing newline.
 // The standard library log.Logger is a Printfer.
 func Pdiff(p Printfer, a, b interface{}) {
+	pdiff(p, false, a, b)
+}
+
+func pdiff(p Printfer, color bool, a, b interface{}) {
 	d := diffPrinter{
-		w:        p,
-		aVisited: make(map[visit]visit),
-		bVisited: make(map[visit]visit),
-		labels:   NewLabels(),
+		diffConfig: defaultConfig,
+		w:          p,
+		color:      color,
+		aVisited:   make(map[visit]visit),
+		bVisited:   make(map[visit]visit),
+		labels:     NewLabels(),
 	}
 	d.diff(reflect.ValueOf(a), reflect.ValueOf(b))
 }
@@ -71,49 +80,146 @@ func Ldiff(l Logfer, a, b interface{}) {
 	Pdiff(&logprintfer{l}, a, b)
 }
 
+// diffConfig holds the options of a Comparator. It is shared by all
+// diffPrinters of a walk and never modified, so a Comparator can be
+// used from several goroutines at once.
+type diffConfig struct {
+	ignoreTypeNameDifference bool
+	customComparators        map[reflect.Type]Equals
+	numericComparator        Float64Equals
+	precision                float64
+	sliceKeys                map[reflect.Type]sliceKeyFunc
+	unorderedSlices          bool
+	unorderedSliceTypes      map[reflect.Type]struct{}
+	ignorePaths              []pathPattern
+	fieldNumeric             map[string]Float64Equals
+	pathNumeric              []numericRule
+	exactIntegers            bool
+	timeEquals               func(a, b time.Time) bool
+	labelNames               []string
+	maxDiffs                 int
+
+	// plans is a cache, safe for concurrent use.
+	plans planCache
+}
+
+// defaultConfig is used by Diff, Fdiff, Pdiff and Ldiff.
+var defaultConfig = &diffConfig{precision: defaultPrecision}
+
 type diffPrinter struct {
+	*diffConfig
+
 	w        Printfer
 	l        string
 	leafName string
-
-	structuredOutput         StructuredDiffer
-	ignoreTypeNameDifference bool
-	customComparators        map[reflect.Type]Equals
-	numericComparator        Float64Equals
-	labels                   Labels
+	// fieldName is the name of the innermost struct field on the path.
+	fieldName string
+
+	structuredOutput StructuredDiffer
+	labels           Labels
+	unorderedTag     bool
+	tagNumeric       Float64Equals
+	budget           *diffBudget
+	color            bool
+	// path is the path of the compared values, tracked only for
+	// structured output.
+	path Path
+	// unlabeled is set by equal if no option needs the path in l,
+	// which is then not built.
+	unlabeled bool
+	// indirect is set for values reached through a pointer, slice,
+	// interface or map, the only ones that can be reached twice.
+	indirect bool
 
 	aVisited map[visit]visit
 	bVisited map[visit]visit
 }
 
+// diffBudget limits the number of differences a walk reports.
+type diffBudget struct {
+	max      int
+	reported int
+	// skipped counts the differences not reported and the values not
+	// compared once the budget was exhausted.
+	skipped int
+	// admitted tells structuredPrint whether the preceding printf was
+	// within the budget.
+	admitted bool
+}
+
+// exhausted reports whether the walk should stop, counting remaining as
+// the number of values it leaves uncompared.
+func (w diffPrinter) exhausted(remaining int) bool {
+	if w.budget == nil || w.budget.reported < w.budget.max {
+		return false
+	}
+	w.budget.skipped += remaining
+	return true
+}
+
 func (w diffPrinter) printf(f string, a ...interface{}) {
+	if _, ok := w.w.(stopPrinter); ok {
+		panic(diffFound{})
+	}
+	if w.budget != nil {
+		if w.budget.admitted = w.budget.reported < w.budget.max; !w.budget.admitted {
+			w.budget.skipped++
+			return
+		}
+		w.budget.reported++
+	}
 	var l string
 	if w.l != "" {
 		l = w.l + ": "
 	}
+	if w.color {
+		f = colorSides(f)
+	}
 	w.w.Printf(l+f, a...)
 }
 
-func (w diffPrinter) structuredPrint(aValue, bValue string) {
+func (w diffPrinter) structuredPrint(kind DiffKind, av, bv reflect.Value, aValue, bValue string) {
+	w.structuredPrintNumeric(kind, av, bv, aValue, bValue, nil)
+}
+
+// numericPrint is structuredPrint for the numbers a and b read from av and bv.
+func (w diffPrinter) numericPrint(av, bv reflect.Value, a, b float64, aValue, bValue string) {
+	if w.structuredOutput != nil {
+		w.structuredPrintNumeric(Changed, av, bv, aValue, bValue, newNumericDelta(a, b))
+	}
+}
+
+func (w diffPrinter) structuredPrintNumeric(kind DiffKind, av, bv reflect.Value, aValue, bValue string, numeric *NumericDelta) {
+	if w.budget != nil && !w.budget.admitted {
+		return
+	}
 	if w.structuredOutput != nil {
 		w.structuredOutput.Print(StructuredDiff{
 			FieldName: w.l,
 			ValueA:    aValue,
 			ValueB:    bValue,
 			Labels:    w.labels.Current(w.l),
+			Kind:      kind,
+			A:         exportedOf(av),
+			B:         exportedOf(bv),
+			Path:      w.path,
+			Numeric:   numeric,
 		})
 	}
 }
 
 func (w diffPrinter) diff(av, bv reflect.Value) {
+	if w.ignored() || w.exhausted(1) {
+		return
+	}
 	if !av.IsValid() && bv.IsValid() {
 		w.printf("nil != %# v", formatter{v: bv, quote: true})
-		w.structuredPrint("nil", fmt.Sprintf("%v", bv))
+		w.structuredPrint(NilMismatch, av, bv, "nil", fmt.Sprintf("%v", bv))
 		return
 	}
 	if av.IsValid() && !bv.IsValid() {
 		w.printf("%# v != nil", formatter{v: av, quote: true})
-		w.structuredPrint(fmt.Sprintf("%v", av), "nil")
+		w.structuredPrint(NilMismatch, av, bv, fmt.Sprintf("%v", av), "nil")
 		return
 	}
 	if !av.IsValid() && !bv.IsValid() {
@@ -124,11 +230,17 @@ func (w diffPrinter) diff(av, bv reflect.Value) {
 	bt := bv.Type()
 	if !w.ignoreTypeNameDifference && at != bt {
 		w.printf("%v != %v", at, bt)
-		w.structuredPrint(fmt.Sprintf("%v", at), fmt.Sprintf("%v", bt))
+		w.structuredPrint(TypeMismatch, av, bv, fmt.Sprintf("%v", at), fmt.Sprintf("%v", bt))
 		return
 	}
-
-	if av.CanAddr() && bv.CanAddr() {
+	p := w.plan(at)
+
+	// Values shared on one side but not on the other are reported as
+	// previously visited. Sharing takes an indirection, so values within
+	// a struct or array reached through none need no tracking.
+	indirect := w.indirect
+	w.indirect = false
+	if indirect && av.CanAddr() && bv.CanAddr() {
 		avis := visit{av.UnsafeAddr(), at}
 		bvis := visit{bv.UnsafeAddr(), bt}
 		var cycle bool
@@ -138,12 +250,12 @@ func (w diffPrinter) diff(av, bv reflect.Value) {
 			cycle = true
 			if vis != bvis {
 				w.printf("%# v (previously visited) != %# v", formatter{v: av, quote: true}, formatter{v: bv, quote: true})
-				w.structuredPrint(fmt.Sprintf("%#v (previously visited) ", av), fmt.Sprintf("%#v", bv))
+				w.structuredPrint(Cycle, av, bv, fmt.Sprintf("%#v (previously visited) ", av), fmt.Sprintf("%#v", bv))
 			}
 		} else if _, ok := w.bVisited[bvis]; ok {
 			cycle = true
 			w.printf("%# v != %# v (previously visited)", formatter{v: av, quote: true}, formatter{v: bv, quote: true})
-			w.structuredPrint(fmt.Sprintf("%#v", av), fmt.Sprintf("%#v (previously visited) ", bv))
+			w.structuredPrint(Cycle, av, bv, fmt.Sprintf("%#v", av), fmt.Sprintf("%#v (previously visited) ", bv))
 		}
 		w.aVisited[avis] = bvis
 		w.bVisited[bvis] = avis
@@ -152,130 +264,170 @@ func (w diffPrinter) diff(av, bv reflect.Value) {
 		}
 	}
 
-	//TODO: Make time comparison adjustable
-	if at.ConvertibleTo(reflect.TypeOf(time.Time{})) && av.CanInterface() {
-		atime := av.Convert(reflect.TypeOf(time.Time{})).Interface().(time.Time)
-		btime := bv.Convert(reflect.TypeOf(time.Time{})).Interface().(time.Time)
-		if atime.String() != btime.String() {
-			w.printf("%v != %v", atime.String(), btime.String())
-			w.structuredPrint(atime.String(), btime.String())
-		}
+	if p.time && av.CanInterface() {
+		w.diffTime(av, bv)
 		return
 	}
 
-	equals, ok := w.customComparators[at]
-	if ok {
-		if !equals(av.Interface(), bv.Interface()) {
+	if p.custom != nil {
+		if !p.custom(av.Interface(), bv.Interface()) {
 			w.printf("%v != %v", av, bv)
-			w.structuredPrint(fmt.Sprintf("%v", av), fmt.Sprintf("%v", bv))
+			w.structuredPrint(Changed, av, bv, fmt.Sprintf("%v", av), fmt.Sprintf("%v", bv))
 		}
 		return
 	}
 
-	if w.numericComparator != nil && at.ConvertibleTo(reflect.TypeOf(float64(0))) && bt.ConvertibleTo(reflect.TypeOf(float64(0))) {
-		if !w.numericComparator(av.Convert(reflect.TypeOf(float64(0))).Float(), bv.Convert(reflect.TypeOf(float64(0))).Float()) {
-			w.printf("%v != %v", av, bv)
-			w.structuredPrint(fmt.Sprintf("%v", av), fmt.Sprintf("%v", bv))
+	if p.numeric && (at == bt || w.plan(bt).numeric) {
+		if equals := w.numericEquals(p); equals != nil {
+			if a, b := av.Convert(float64Type).Float(), bv.Convert(float64Type).Float(); !equals(a, b) {
+				w.printf("%v != %v", av, bv)
+				w.numericPrint(av, bv, a, b, fmt.Sprintf("%v", av), fmt.Sprintf("%v", bv))
+			}
+			return
 		}
-		return
 	}
 
 	switch kind := at.Kind(); kind {
 	case reflect.Bool:
 		if a, b := av.Bool(), bv.Bool(); a != b {
 			w.printf("%v != %v", a, b)
-			w.structuredPrint(fmt.Sprintf("%v", a), fmt.Sprintf("%v", b))
+			w.structuredPrint(Changed, av, bv, fmt.Sprintf("%v", a), fmt.Sprintf("%v", b))
 		}
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
 		if a, b := av.Int(), bv.Int(); a != b {
 			w.printf("%d != %d", a, b)
-			w.structuredPrint(fmt.Sprintf("%d", a), fmt.Sprintf("%d", b))
+			w.numericPrint(av, bv, float64(a), float64(b), fmt.Sprintf("%d", a), fmt.Sprintf("%d", b))
 		}
 	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
 		if a, b := av.Uint(), bv.Uint(); a != b {
 			w.printf("%d != %d", a, b)
-			w.structuredPrint(fmt.Sprintf("%d", a), fmt.Sprintf("%d", b))
+			w.numericPrint(av, bv, float64(a), float64(b), fmt.Sprintf("%d", a), fmt.Sprintf("%d", b))
 		}
 	case reflect.Float32, reflect.Float64:
 		if a, b := av.Float(), bv.Float(); a != b {
 			w.printf("%v != %v", a, b)
-			w.structuredPrint(fmt.Sprintf("%v", a), fmt.Sprintf("%v", b))
+			w.numericPrint(av, bv, a, b, fmt.Sprintf("%v", a), fmt.Sprintf("%v", b))
 		}
 	case reflect.Complex64, reflect.Complex128:
 		if a, b := av.Complex(), bv.Complex(); a != b {
 			w.printf("%v != %v", a, b)
-			w.structuredPrint(fmt.Sprintf("%v", a), fmt.Sprintf("%v", b))
+			w.structuredPrint(Changed, av, bv, fmt.Sprintf("%v", a), fmt.Sprintf("%v", b))
 		}
 	case reflect.Array:
 		n := av.Len()
-		for i := 0; i < n; i++ {
-			w.relabel(fmt.Sprintf("[%d]", i)).diff(av.Index(i), bv.Index(i))
+		if n != bv.Len() {
+			// Arrays of different types, compared under WithIgnoreTypeNameDiffs.
+			w.printf("%# v != %# v", formatter{v: av, quote: true}, formatter{v: bv, quote: true})
+			w.structuredPrint(LengthMismatch, av, bv, fmt.Sprintf("%v", av), fmt.Sprintf("%v", bv))
+			return
+		}
+		for i := 0; i < n && !w.exhausted(n-i); i++ {
+			w.descendIndex(i).diff(av.Index(i), bv.Index(i))
 		}
 	case reflect.Chan, reflect.Func, reflect.UnsafePointer:
 		if a, b := av.Pointer(), bv.Pointer(); a != b {
 			w.printf("%#x != %#x", a, b)
-			w.structuredPrint(fmt.Sprintf("%#x", a), fmt.Sprintf("%#x", b))
+			w.structuredPrint(Changed, av, bv, fmt.Sprintf("%#x", a), fmt.Sprintf("%#x", b))
 		}
 	case reflect.Interface:
-		w.diff(av.Elem(), bv.Elem())
+		w.descend("", unwrapStep).through().diff(av.Elem(), bv.Elem())
 	case reflect.Map:
 		ak, both, bk := keyDiff(av.MapKeys(), bv.MapKeys())
-		for _, k := range ak {
-			w := w.relabel(fmt.Sprintf("[%#v]", k))
+		for i, k := range ak {
+			if w.exhausted(len(ak) - i) {
+				break
+			}
+			w := w.descendKey(k)
+			if w.ignored() {
+				continue
+			}
 			w.printf("%q != (missing)", av.MapIndex(k))
-			w.structuredPrint(fmt.Sprintf("%q", av.MapIndex(k)), "(missing)")
+			w.structuredPrint(Removed, av.MapIndex(k), reflect.Value{}, fmt.Sprintf("%q", av.MapIndex(k)), "(missing)")
 		}
-		for _, k := range both {
-			w := w.relabel(fmt.Sprintf("[%#v]", k))
+		for i, k := range both {
+			if w.exhausted(len(both) - i) {
+				break
+			}
+			w := w.descendKey(k).through()
 			w.diff(av.MapIndex(k), bv.MapIndex(k))
 		}
-		for _, k := range bk {
-			w := w.relabel(fmt.Sprintf("[%#v]", k))
+		for i, k := range bk {
+			if w.exhausted(len(bk) - i) {
+				break
+			}
+			w := w.descendKey(k)
+			if w.ignored() {
+				continue
+			}
 			w.printf("(missing) != %q", bv.MapIndex(k))
-			w.structuredPrint("(missing)", fmt.Sprintf("%q", bv.MapIndex(k)))
+			w.structuredPrint(Added, reflect.Value{}, bv.MapIndex(k), "(missing)", fmt.Sprintf("%q", bv.MapIndex(k)))
 		}
 	case reflect.Ptr:
 		switch {
 		case av.IsNil() && !bv.IsNil():
 			w.printf("nil != %# v", formatter{v: bv, quote: true})
-			w.structuredPrint("nil", fmt.Sprintf("%#v", bv))
+			w.structuredPrint(NilMismatch, av, bv, "nil", fmt.Sprintf("%#v", bv))
 		case !av.IsNil() && bv.IsNil():
 			w.printf("%# v != nil", formatter{v: av, quote: true})
-			w.structuredPrint(fmt.Sprintf("%#v", av), "nil")
+			w.structuredPrint(NilMismatch, av, bv, fmt.Sprintf("%#v", av), "nil")
 		case !av.IsNil() && !bv.IsNil():
-			w.diff(av.Elem(), bv.Elem())
+			w.descend("", derefStep).through().diff(av.Elem(), bv.Elem())
 		}
 	case reflect.Slice:
 		if bv.Kind() != reflect.Slice {
 			w.printf("%v != %v", at, bt)
-			w.structuredPrint(fmt.Sprintf("%v", at), fmt.Sprintf("%v", bt))
+			w.structuredPrint(TypeMismatch, av, bv, fmt.Sprintf("%v", at), fmt.Sprintf("%v", bt))
+			break
+		}
+		if p.sliceKey != nil {
+			w.diffSliceByKey(av, bv, p.sliceKey)
+			break
+		}
+		if p.unordered || w.unorderedTag {
+			w.diffSliceUnordered(av, bv)
 			break
 		}
 		lenA := av.Len()
 		lenB := bv.Len()
 		if lenA != lenB {
-			w.printf("%s[%d] != %s[%d]", av.Type(), lenA, bv.Type(), lenB)
-			w.structuredPrint(fmt.Sprintf("%s[%d]", av.Type(), lenA), fmt.Sprintf("%s[%d]", bv.Type(), lenB))
+			w.diffSlice(av, bv)
 			break
 		}
-		for i := 0; i < lenA; i++ {
-			w.relabel(fmt.Sprintf("[%d]", i)).diff(av.Index(i), bv.Index(i))
+		for i := 0; i < lenA && !w.exhausted(lenA-i); i++ {
+			w.descendIndex(i).through().diff(av.Index(i), bv.Index(i))
 		}
 	case reflect.String:
 		if a, b := av.String(), bv.String(); a != b {
 			w.printf("%q != %q", a, b)
-			w.structuredPrint(fmt.Sprintf("%q", a), fmt.Sprintf("%q", b))
+			w.structuredPrint(Changed, av, bv, fmt.Sprintf("%q", a), fmt.Sprintf("%q", b))
 		}
 	case reflect.Struct:
-		for i := 0; i < av.NumField(); i++ {
-			if at.Field(i).Type.Kind() == reflect.String {
-				w.labels.SetIfExists(w.l, at.Field(i).Name, av.Field(i).String())
+		labeled := len(p.labelFields) > 0 && !w.unlabeled
+		if labeled {
+			for _, i := range p.labelFields {
+				if r, ok := w.labels.(labelRegisterer); ok && p.fields[i].label {
+					r.register(p.fields[i].name)
+				}
+				w.labels.SetIfExists(w.l, p.fields[i].name, av.Field(i).String())
 			}
 		}
-		for i := 0; i < av.NumField(); i++ {
-			w.relabel(at.Field(i).Name).diff(av.Field(i), bv.Field(i))
+		for i, f := range p.fields {
+			if w.exhausted(len(p.fields) - i) {
+				break
+			}
+			if f.ignore {
+				continue
+			}
+			fw := w.descend(f.name, fieldStep(f.name))
+			if f.numeric != nil {
+				fw.tagNumeric = f.numeric
+			}
+			fw.unorderedTag = f.unordered
+			fw.diff(av.Field(i), bv.Field(i))
+		}
+		if labeled {
+			w.labels.Clear(w.l)
 		}
-		w.labels.Clear(w.l)
 	default:
 		panic("unknown reflect Kind: " + kind.String())
 	}
@@ -294,18 +446,105 @@ func getReadableTimeCopy(av reflect.Value) time.Time {
 	return time.Time{}
 }
 
+var float64Type = reflect.TypeOf(float64(0))
+
+// numericEquals returns the comparator for numbers planned by p at the
+// current path, or nil if they are compared exactly. Struct tags take
+// precedence over path tolerances, path tolerances over field tolerances
+// and those over the global numeric comparator.
+func (w diffPrinter) numericEquals(p *typePlan) Float64Equals {
+	if w.tagNumeric != nil {
+		return w.tagNumeric
+	}
+	if len(w.pathNumeric) > 0 {
+		elems := splitPath(w.l)
+		for _, r := range w.pathNumeric {
+			if r.pattern.match(elems) {
+				return r.equals
+			}
+		}
+	}
+	if equals, ok := w.fieldNumeric[w.fieldName]; ok {
+		return equals
+	}
+	if w.exactIntegers && p.integer {
+		return nil
+	}
+	return w.numericComparator
+}
+
+func indexLabel(i int) string {
+	return "[" + strconv.Itoa(i) + "]"
+}
+
 const sep = "."
 
 func (d diffPrinter) relabel(name string) (d1 diffPrinter) {
 	d1 = d
-	if d.l != "" && name[0] != '[' {
-		d1.l += sep
+	if !d.unlabeled {
+		if d.l != "" && name[0] != '[' {
+			d1.l += sep
+		}
+		d1.l += name
 	}
-	d1.l += name
 	d1.leafName = name
+	if name[0] != '[' {
+		d1.fieldName = name
+	}
+	d1.unorderedTag = false
 	return d1
 }
 
+// ignored reports whether differences at the current path are skipped,
+// see WithIgnorePaths.
+func (d diffPrinter) ignored() bool {
+	return matchAny(d.ignorePaths, d.l)
+}
+
+// descend returns d for the value at step s below the current one,
+// named name in labels, or at the current label if name is empty.
+func (d diffPrinter) descend(name string, s PathStep) diffPrinter {
+	if name != "" {
+		d = d.relabel(name)
+	}
+	if d.structuredOutput != nil {
+		d.path = d.path.with(s)
+	}
+	return d
+}
+
+// through returns d for a value reached through an indirection.
+func (d diffPrinter) through() diffPrinter {
+	d.indirect = true
+	return d
+}
+
+// descendIndex returns d for the slice or array element at index i.
+func (d diffPrinter) descendIndex(i int) diffPrinter {
+	if d.unlabeled {
+		d.unorderedTag = false
+	} else {
+		d = d.relabel(indexLabel(i))
+	}
+	if d.structuredOutput != nil {
+		d.path = d.path.with(indexStep(i))
+	}
+	return d
+}
+
+// descendKey returns d for the map value under key k.
+func (d diffPrinter) descendKey(k reflect.Value) diffPrinter {
+	if d.unlabeled {
+		d.unorderedTag = false
+	} else {
+		d = d.relabel(fmt.Sprintf("[%#v]", k))
+	}
+	if d.structuredOutput != nil {
+		d.path = d.path.with(keyStep(interfaceOf(k)))
+	}
+	return d
+}
+
 func getValueForRead(src reflect.Value) reflect.Value {
 	rs := reflect.ValueOf(src)
 	rs2 := reflect.New(rs.Type()).Elem()
@@ -317,7 +556,8 @@ func getValueForRead(src reflect.Value) reflect.Value {
 }
 
 // keyEqual compares a and b for equality.
-// Both a and b must be valid map keys.
+// Both a and b must be valid map keys, or slice keys (see WithSliceKey),
+// which may also be or hold slices and maps compared element by element.
 func keyEqual(av, bv reflect.Value) bool {
 	if !av.IsValid() && !bv.IsValid() {
 		return true
@@ -363,31 +603,74 @@ func keyEqual(av, bv reflect.Value) bool {
 			}
 		}
 		return true
+	case reflect.Slice:
+		if av.Len() != bv.Len() {
+			return false
+		}
+		for i := 0; i < av.Len(); i++ {
+			if !keyEqual(av.Index(i), bv.Index(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Map:
+		if av.Len() != bv.Len() {
+			return false
+		}
+		for _, k := range av.MapKeys() {
+			if b := bv.MapIndex(k); !b.IsValid() || !keyEqual(av.MapIndex(k), b) {
+				return false
+			}
+		}
+		return true
+	case reflect.Func:
+		return av.IsNil() && bv.IsNil()
 	default:
 		panic("invalid map key type " + av.Type().String())
 	}
 }
 
 func keyDiff(a, b []reflect.Value) (ak, both, bk []reflect.Value) {
+	// Index the keys of b that can be hashed and fall back to keyEqual for the rest.
+	indexed := make(map[interface{}]bool, len(b))
+	var unindexed []reflect.Value
+	for _, bv := range b {
+		if k, ok := keyIndex(bv); ok {
+			indexed[k] = false
+		} else {
+			unindexed = append(unindexed, bv)
+		}
+	}
 	for _, av := range a {
 		inBoth := false
-		for _, bv := range b {
-			if keyEqual(av, bv) {
-				inBoth = true
-				both = append(both, av)
-				break
+		if k, ok := keyIndex(av); ok {
+			if _, inBoth = indexed[k]; inBoth {
+				indexed[k] = true
+			}
+		} else {
+			for _, bv := range unindexed {
+				if keyEqual(av, bv) {
+					inBoth = true
+					break
+				}
 			}
 		}
-		if !inBoth {
+		if inBoth {
+			both = append(both, av)
+		} else {
 			ak = append(ak, av)
 		}
 	}
 	for _, bv := range b {
 		inBoth := false
-		for _, av := range a {
-			if keyEqual(av, bv) {
-				inBoth = true
-				break
+		if k, ok := keyIndex(bv); ok {
+			inBoth = indexed[k]
+		} else {
+			for _, av := range a {
+				if keyEqual(av, bv) {
+					inBoth = true
+					break
+				}
 			}
 		}
 		if !inBoth {
@@ -396,3 +679,44 @@ func keyDiff(a, b []reflect.Value) (ak, both, bk []reflect.Value) {
 	}
 	return
 }
+
+// exportedOf returns the value held by v, or nil if v was obtained
+// through unexported struct fields, which reflect keeps read-only.
+func exportedOf(v reflect.Value) interface{} {
+	if !v.IsValid() || !v.CanInterface() {
+		return nil
+	}
+	return v.Interface()
+}
+
+// interfaceOf returns the value held by v even if v was obtained
+// through unexported struct fields. Values that can be neither
+// addressed nor copied are returned as nil.
+func interfaceOf(v reflect.Value) interface{} {
+	switch {
+	case !v.IsValid():
+		return nil
+	case v.CanInterface():
+		return v.Interface()
+	case v.CanAddr():
+		return reflect.NewAt(v.Type(), unsafe.Pointer(v.UnsafeAddr())).Elem().Interface()
+	}
+	c := reflect.New(v.Type()).Elem()
+	switch v.Kind() {
+	case reflect.Bool:
+		c.SetBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		c.SetInt(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		c.SetUint(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		c.SetFloat(v.Float())
+	case reflect.Complex64, reflect.Complex128:
+		c.SetComplex(v.Complex())
+	case reflect.String:
+		c.SetString(v.String())
+	default:
+		return nil
+	}
+	return c.Interface()
+}
@@ -55,8 +55,11 @@ var diffs = []difftest{
 	{S{S: new(S)}, S{S: &S{A: 1}}, []string{`S.A: 0 != 1`}},
 	{S{}, S{I: 0}, []string{`I: nil != int(0)`}},
 	{S{I: 1}, S{I: "x"}, []string{`I: int != string`}},
-	{S{}, S{C: []int{1}}, []string{`C: []int[0] != []int[1]`}},
-	{S{C: []int{}}, S{C: []int{1}}, []string{`C: []int[0] != []int[1]`}},
+	{S{}, S{C: []int{1}}, []string{`C[0]: (missing) != int(1)`}},
+	{S{C: []int{}}, S{C: []int{1}}, []string{`C[0]: (missing) != int(1)`}},
+	{S{C: []int{1, 2, 3}}, S{C: []int{1, 3}}, []string{`C[1]: int(2) != (missing)`}},
+	{S{C: []int{1, 3}}, S{C: []int{1, 2, 3}}, []string{`C[1]: (missing) != int(2)`}},
+	{S{C: []int{1, 2, 3}}, S{C: []int{0, 1, 2, 4, 5}}, []string{`C[0]: (missing) != int(0)`, `C[2]: 3 != 4`, `C[4]: (missing) != int(5)`}},
 	{S{C: []int{1, 2, 3}}, S{C: []int{1, 2, 4}}, []string{`C[2]: 3 != 4`}},
 	{S{}, S{A: 1, S: new(S)}, []string{`A: 0 != 1`, `S: nil != &pretty.S{}`}},
 
@@ -235,6 +238,12 @@ func TestDiffCycle(t *testing.T) {
     i:  1,
     R:  &pretty.I{(CYCLIC REFERENCE)},
 } (previously visited)`})
+
+	// A value shared within a and not within b
+	p, q, r := &N{1}, &N{1}, &N{1}
+	expectDiffOutput(t, []*N{p, p}, []*N{q, r}, []string{
+		`[1]: pretty.N{N:1} (previously visited) != pretty.N{N:1}`,
+	})
 }
 
 func diffdiff(t *testing.T, got, exp []string) {
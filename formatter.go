@@ -15,6 +15,7 @@ type formatter struct {
 	v     reflect.Value
 	force bool
 	quote bool
+	color bool
 }
 
 // Formatter makes a wrapper, f, that will format x as go source with line
@@ -30,6 +31,12 @@ func Formatter(x interface{}) (f fmt.Formatter) {
 	return formatter{v: reflect.ValueOf(x), quote: true}
 }
 
+// ColorFormatter is like Formatter, but colors types, field names,
+// strings and numbers with ANSI escape codes when formatting with "%# v".
+func ColorFormatter(x interface{}) (f fmt.Formatter) {
+	return formatter{v: reflect.ValueOf(x), quote: true, color: true}
+}
+
 func (fo formatter) String() string {
 	return fmt.Sprint(fo.v.Interface()) // unwrap it
 }
@@ -54,7 +61,7 @@ func (fo formatter) passThrough(f fmt.State, c rune) {
 func (fo formatter) Format(f fmt.State, c rune) {
 	if fo.force || c == 'v' && f.Flag('#') && f.Flag(' ') {
 		w := tabwriter.NewWriter(f, 4, 4, 1, ' ', 0)
-		p := &printer{tw: w, Writer: w, visited: make(map[visit]int)}
+		p := &printer{tw: w, Writer: w, visited: make(map[visit]int), color: fo.color}
 		p.printValue(fo.v, true, fo.quote)
 		w.Flush()
 		return
@@ -67,6 +74,7 @@ type printer struct {
 	tw      *tabwriter.Writer
 	visited map[visit]int
 	depth   int
+	color   bool
 }
 
 func (p *printer) indent() *printer {
@@ -77,12 +85,26 @@ func (p *printer) indent() *printer {
 }
 
 func (p *printer) printInline(v reflect.Value, x interface{}, showType bool) {
+	color := colorNumber
+	if v.Kind() == reflect.Bool {
+		color = colorConst
+	}
 	if showType {
-		io.WriteString(p, v.Type().String())
-		fmt.Fprintf(p, "(%#v)", x)
+		p.colored(colorType, v.Type().String())
+		writeByte(p, '(')
+		p.colored(color, fmt.Sprintf("%#v", x))
+		writeByte(p, ')')
 	} else {
-		fmt.Fprintf(p, "%#v", x)
+		p.colored(color, fmt.Sprintf("%#v", x))
+	}
+}
+
+// colored writes s in color if p colors its output.
+func (p *printer) colored(color, s string) {
+	if p.color {
+		s = color + s + colorReset
 	}
+	io.WriteString(p, s)
 }
 
 // printValue must keep track of already-printed pointer values to avoid
@@ -135,13 +157,13 @@ func (p *printer) printValue(v reflect.Value, showType, quote bool) {
 	case reflect.Float32, reflect.Float64:
 		p.printInline(v, v.Float(), showType)
 	case reflect.Complex64, reflect.Complex128:
-		fmt.Fprintf(p, "%#v", v.Complex())
+		p.colored(colorNumber, fmt.Sprintf("%#v", v.Complex()))
 	case reflect.String:
 		p.fmtString(v.String(), quote)
 	case reflect.Map:
 		t := v.Type()
 		if showType {
-			io.WriteString(p, t.String())
+			p.colored(colorType, t.String())
 		}
 		writeByte(p, '{')
 		if nonzero(v) {
@@ -186,7 +208,7 @@ func (p *printer) printValue(v reflect.Value, showType, quote bool) {
 		}
 
 		if showType {
-			io.WriteString(p, t.String())
+			p.colored(colorType, t.String())
 		}
 		writeByte(p, '{')
 		if nonzero(v) {
@@ -199,7 +221,7 @@ func (p *printer) printValue(v reflect.Value, showType, quote bool) {
 			for i := 0; i < v.NumField(); i++ {
 				showTypeInStruct := true
 				if f := t.Field(i); f.Name != "" {
-					io.WriteString(pp, f.Name)
+					pp.colored(colorField, f.Name)
 					writeByte(pp, ':')
 					if expand {
 						writeByte(pp, '\t')
@@ -221,26 +243,30 @@ func (p *printer) printValue(v reflect.Value, showType, quote bool) {
 	case reflect.Interface:
 		switch e := v.Elem(); {
 		case e.Kind() == reflect.Invalid:
-			io.WriteString(p, "nil")
+			p.colored(colorConst, "nil")
 		case e.IsValid():
 			pp := *p
 			pp.depth++
 			pp.printValue(e, showType, true)
 		default:
-			io.WriteString(p, v.Type().String())
-			io.WriteString(p, "(nil)")
+			p.colored(colorType, v.Type().String())
+			io.WriteString(p, "(")
+			p.colored(colorConst, "nil")
+			io.WriteString(p, ")")
 		}
 	case reflect.Array, reflect.Slice:
 		t := v.Type()
 		if showType {
-			io.WriteString(p, t.String())
+			p.colored(colorType, t.String())
 		}
 		if v.Kind() == reflect.Slice && v.IsNil() && showType {
-			io.WriteString(p, "(nil)")
+			io.WriteString(p, "(")
+			p.colored(colorConst, "nil")
+			io.WriteString(p, ")")
 			break
 		}
 		if v.Kind() == reflect.Slice && v.IsNil() {
-			io.WriteString(p, "nil")
+			p.colored(colorConst, "nil")
 			break
 		}
 		writeByte(p, '{')
@@ -267,8 +293,10 @@ func (p *printer) printValue(v reflect.Value, showType, quote bool) {
 		e := v.Elem()
 		if !e.IsValid() {
 			writeByte(p, '(')
-			io.WriteString(p, v.Type().String())
-			io.WriteString(p, ")(nil)")
+			p.colored(colorType, v.Type().String())
+			io.WriteString(p, ")(")
+			p.colored(colorConst, "nil")
+			io.WriteString(p, ")")
 		} else {
 			pp := *p
 			pp.depth++
@@ -279,18 +307,20 @@ func (p *printer) printValue(v reflect.Value, showType, quote bool) {
 		x := v.Pointer()
 		if showType {
 			writeByte(p, '(')
-			io.WriteString(p, v.Type().String())
-			fmt.Fprintf(p, ")(%#v)", x)
+			p.colored(colorType, v.Type().String())
+			io.WriteString(p, ")(")
+			p.colored(colorNumber, fmt.Sprintf("%#v", x))
+			io.WriteString(p, ")")
 		} else {
-			fmt.Fprintf(p, "%#v", x)
+			p.colored(colorNumber, fmt.Sprintf("%#v", x))
 		}
 	case reflect.Func:
-		io.WriteString(p, v.Type().String())
+		p.colored(colorType, v.Type().String())
 		io.WriteString(p, " {...}")
 	case reflect.UnsafePointer:
 		p.printInline(v, v.Pointer(), showType)
 	case reflect.Invalid:
-		io.WriteString(p, "nil")
+		p.colored(colorConst, "nil")
 	}
 }
 
@@ -337,7 +367,8 @@ func labelType(t reflect.Type) bool {
 
 func (p *printer) fmtString(s string, quote bool) {
 	if quote {
-		s = strconv.Quote(s)
+		p.colored(colorString, strconv.Quote(s))
+		return
 	}
 	io.WriteString(p, s)
 }
@@ -0,0 +1,172 @@
+package pretty
+
+import (
+	"html/template"
+	"io"
+	"strings"
+)
+
+// HTMLReport describes a standalone HTML page presenting structured diffs.
+type HTMLReport struct {
+	// Title of the page, "Differences" if empty.
+	Title string
+	// Diffs are the differences to present, e.g. from Comparator.StructuredDiff.
+	Diffs []StructuredDiff
+	// A and B, if set, are the compared values, shown pretty-printed side by side.
+	A, B interface{}
+}
+
+// WriteHTML writes the report to w as a page that needs no other files.
+// Differences are grouped by their labels, and within a group arranged
+// in a collapsible tree following their field names.
+func (r HTMLReport) WriteHTML(w io.Writer) error {
+	page := htmlPage{Title: r.Title, Total: len(r.Diffs)}
+	if page.Title == "" {
+		page.Title = "Differences"
+	}
+	if r.A != nil || r.B != nil {
+		page.Values = true
+		page.A, page.B = Sprint(r.A), Sprint(r.B)
+	}
+
+	groups := make(map[string]*htmlGroup)
+	for _, d := range r.Diffs {
+		key := labelsKey(d.Labels)
+		g, ok := groups[key]
+		if !ok {
+			g = &htmlGroup{Labels: key, Root: &htmlNode{}}
+			groups[key] = g
+			page.Groups = append(page.Groups, g)
+		}
+		g.Root.add(splitPath(d.FieldName), d)
+	}
+	return htmlTemplate.Execute(w, page)
+}
+
+type htmlPage struct {
+	Title  string
+	Total  int
+	Groups []*htmlGroup
+	Values bool
+	A, B   string
+}
+
+type htmlGroup struct {
+	Labels string
+	Root   *htmlNode
+}
+
+// htmlNode is a path element in the tree of differences of a group.
+type htmlNode struct {
+	Name     string
+	Count    int
+	Diffs    []htmlDiff
+	Children []*htmlNode
+	index    map[string]*htmlNode
+}
+
+type htmlDiff struct {
+	Name, Path, A, B string
+}
+
+// add files d under the node at path elems below n.
+func (n *htmlNode) add(elems []string, d StructuredDiff) {
+	n.Count++
+	if len(elems) <= 1 {
+		name := d.FieldName
+		if len(elems) == 1 {
+			name = elems[0]
+		}
+		n.Diffs = append(n.Diffs, htmlDiff{Name: name, Path: d.FieldName, A: d.ValueA, B: d.ValueB})
+		return
+	}
+	c, ok := n.index[elems[0]]
+	if !ok {
+		if n.index == nil {
+			n.index = make(map[string]*htmlNode)
+		}
+		c = &htmlNode{Name: elems[0]}
+		n.index[elems[0]] = c
+		n.Children = append(n.Children, c)
+	}
+	c.add(elems[1:], d)
+}
+
+// setLabels returns the labels that have a value. StructuredDiff.Labels
+// holds every label name, with an empty value outside the structs
+// defining it.
+func setLabels(labels []Label) []Label {
+	var set []Label
+	for _, l := range labels {
+		if l.Value != "" {
+			set = append(set, l)
+		}
+	}
+	return set
+}
+
+func labelsKey(labels []Label) string {
+	labels = setLabels(labels)
+	if len(labels) == 0 {
+		return "(no labels)"
+	}
+	parts := make([]string, len(labels))
+	for i, l := range labels {
+		parts[i] = l.Name + "=" + l.Value
+	}
+	return strings.Join(parts, ", ")
+}
+
+var htmlTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 1em 2em; }
+details { margin-left: 1.2em; }
+summary { cursor: pointer; }
+.count { color: #777; }
+table { border-collapse: collapse; margin: 0.3em 0 0.3em 1.2em; }
+td, th { border: 1px solid #ddd; padding: 0.2em 0.6em; text-align: left; vertical-align: top; font-family: monospace; white-space: pre-wrap; }
+td.a { background: #ffecec; }
+td.b { background: #eaffea; }
+.values { display: grid; grid-template-columns: 1fr 1fr; gap: 1em; }
+.values pre { background: #f6f6f6; padding: 0.5em; overflow: auto; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p>{{.Total}} differences in {{len .Groups}} groups.</p>
+{{range .Groups}}
+<details open>
+<summary><strong>{{.Labels}}</strong> <span class="count">({{.Root.Count}})</span></summary>
+{{template "node" .Root}}
+</details>
+{{end}}
+{{if .Values}}
+<h2>Values</h2>
+<div class="values">
+<div><h3>A</h3><pre>{{.A}}</pre></div>
+<div><h3>B</h3><pre>{{.B}}</pre></div>
+</div>
+{{end}}
+</body>
+</html>
+{{define "node"}}
+{{- if .Diffs}}
+<table>
+<tr><th>Field</th><th>A</th><th>B</th></tr>
+{{- range .Diffs}}
+<tr title="{{.Path}}"><td>{{.Name}}</td><td class="a">{{.A}}</td><td class="b">{{.B}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- range .Children}}
+<details open>
+<summary>{{.Name}} <span class="count">({{.Count}})</span></summary>
+{{template "node" .}}
+</details>
+{{- end}}
+{{end}}
+`))
@@ -0,0 +1,54 @@
+package pretty
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTMLReport(t *testing.T) {
+	type line struct {
+		SKU   string
+		Price float64
+	}
+	type order struct {
+		ID    string
+		Note  string
+		Lines []line
+	}
+	a := []order{{ID: "o1", Note: "<b>", Lines: []line{{"x", 1}, {"y", 2}}}, {ID: "o2", Lines: []line{{"z", 3}}}}
+	b := []order{{ID: "o1", Note: "</b>", Lines: []line{{"x", 1.5}, {"y", 2.5}}}, {ID: "o2", Lines: []line{{"z", 4}}}}
+	diffs, _ := NewCustomDiff(WithLabelFields("ID")).StructuredDiff(a, b)
+
+	var buf bytes.Buffer
+	err := HTMLReport{Diffs: diffs, A: a, B: b}.WriteHTML(&buf)
+	assert.NoError(t, err)
+	page := buf.String()
+
+	assert.True(t, strings.HasPrefix(page, "<!DOCTYPE html>"))
+	assert.Contains(t, page, "<title>Differences</title>")
+	assert.Contains(t, page, "<p>4 differences in 2 groups.</p>")
+	assert.Contains(t, page, "<summary><strong>ID=o1</strong> <span class=\"count\">(3)</span></summary>")
+	assert.Contains(t, page, "<summary><strong>ID=o2</strong> <span class=\"count\">(1)</span></summary>")
+	assert.Contains(t, page, "<summary>Lines <span class=\"count\">(2)</span></summary>")
+	assert.Contains(t, page, `<tr title="[0].Lines[1].Price"><td>Price</td><td class="a">2</td><td class="b">2.5</td></tr>`)
+	assert.Contains(t, page, `<td class="a">&#34;&lt;b&gt;&#34;</td>`, "values are escaped")
+	assert.Contains(t, page, "<h3>A</h3><pre>[]pretty.order{")
+	assert.NotContains(t, page, "<script")
+
+	type batch struct {
+		Name   string
+		Orders []order
+	}
+	diffs, _ = NewCustomDiff(WithLabelFields("ID")).StructuredDiff(batch{"n1", a}, batch{"n2", a})
+	buf.Reset()
+	assert.NoError(t, HTMLReport{Diffs: diffs}.WriteHTML(&buf))
+	assert.Contains(t, buf.String(), "<summary><strong>(no labels)</strong>", "empty labels are left out")
+
+	buf.Reset()
+	assert.NoError(t, HTMLReport{Title: "Nightly"}.WriteHTML(&buf))
+	assert.Contains(t, buf.String(), "<p>0 differences in 0 groups.</p>")
+	assert.NotContains(t, buf.String(), "<h2>Values</h2>")
+}
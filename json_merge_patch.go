@@ -0,0 +1,106 @@
+package pretty
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// JSONMergePatch returns the RFC 7386 JSON Merge Patch turning the JSON
+// encoding of a into that of b, where patch was computed from a to b, so
+// values the Comparator considers equal are left out. The document holds
+// only the object keys that changed, with null for removed keys. Arrays
+// cannot be merged, so a changed array is replaced as a whole. A value
+// that became null is removed by the merge patch.
+func JSONMergePatch(patch Patch, a, b interface{}) (interface{}, error) {
+	aDoc, err := jsonDocument(a)
+	if err != nil {
+		return nil, err
+	}
+	bDoc, err := jsonDocument(b)
+	if err != nil {
+		return nil, err
+	}
+
+	t := reflect.TypeOf(b)
+	var merge interface{} = map[string]interface{}{}
+	for _, o := range patch {
+		keys, _, ok := jsonKeys(o.Path, t)
+		if !ok {
+			continue
+		}
+		merge = mergeKeys(merge, aDoc, bDoc, keys)
+	}
+	return merge, nil
+}
+
+// MarshalJSONMergePatch returns the encoded JSON Merge Patch of patch, see JSONMergePatch.
+func MarshalJSONMergePatch(patch Patch, a, b interface{}) ([]byte, error) {
+	merge, err := JSONMergePatch(patch, a, b)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(merge)
+}
+
+// jsonDocument returns v as decoded by encoding/json into an interface{}.
+func jsonDocument(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("pretty: cannot encode %T: %v", v, err)
+	}
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("pretty: cannot decode %T: %v", v, err)
+	}
+	return doc, nil
+}
+
+// mergeKeys records in merge that the value at keys changed from aDoc to
+// bDoc and returns the updated merge patch. Changes below an array
+// replace the whole array.
+func mergeKeys(merge, aDoc, bDoc interface{}, keys []string) interface{} {
+	a, _ := aDoc.(map[string]interface{})
+	b, isObject := bDoc.(map[string]interface{})
+	m, isMerge := merge.(map[string]interface{})
+	if len(keys) == 0 || !isObject || !isMerge {
+		return replacement(aDoc, bDoc)
+	}
+	k := keys[0]
+	bv, ok := b[k]
+	switch {
+	case !ok:
+		// Removed, or omitted by encoding/json, e.g. for omitempty.
+		m[k] = nil
+	case len(keys) == 1:
+		m[k] = replacement(a[k], bv)
+	default:
+		sub, ok := m[k]
+		if !ok {
+			sub = map[string]interface{}{}
+		}
+		m[k] = mergeKeys(sub, a[k], bv, keys[1:])
+	}
+	return m
+}
+
+// replacement returns the merge patch turning aDoc into bDoc as a whole.
+// Objects are merged into the target, so keys of aDoc missing from bDoc
+// have to be removed explicitly.
+func replacement(aDoc, bDoc interface{}) interface{} {
+	a, aObject := aDoc.(map[string]interface{})
+	b, bObject := bDoc.(map[string]interface{})
+	if !aObject || !bObject {
+		return bDoc
+	}
+	m := make(map[string]interface{}, len(b))
+	for k, bv := range b {
+		m[k] = replacement(a[k], bv)
+	}
+	for k := range a {
+		if _, ok := b[k]; !ok {
+			m[k] = nil
+		}
+	}
+	return m
+}
@@ -0,0 +1,65 @@
+package pretty
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSONMergePatch(t *testing.T) {
+	type address struct {
+		City string `json:"city"`
+		Zip  string `json:"zip,omitempty"`
+	}
+	type customer struct {
+		Name    string                 `json:"name"`
+		Email   string                 `json:"email,omitempty"`
+		Address *address               `json:"address"`
+		Tags    []string               `json:"tags"`
+		Score   float64                `json:"score"`
+		Extra   map[string]interface{} `json:"extra"`
+	}
+	a := customer{
+		Name:    "Ann",
+		Email:   "ann@example.com",
+		Address: &address{City: "Berlin", Zip: "10115"},
+		Tags:    []string{"a", "b"},
+		Score:   1.0,
+		Extra:   map[string]interface{}{"keep": 1, "drop": 2, "obj": map[string]interface{}{"x": 1, "y": 2}},
+	}
+	b := customer{
+		Name:    "Ann",
+		Address: &address{City: "Munich", Zip: "10115"},
+		Tags:    []string{"a", "c"},
+		Score:   1.001,
+		Extra:   map[string]interface{}{"keep": 1, "obj": "flat", "new": true},
+	}
+	c := NewCustomDiff(WithNumericEpsilon(0.01))
+
+	got, err := MarshalJSONMergePatch(c.Patch(a, b), a, b)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"email": null,
+		"address": {"city": "Munich"},
+		"tags": ["a", "c"],
+		"extra": {"drop": null, "obj": "flat", "new": true}
+	}`, string(got))
+
+	b.Extra["obj"] = map[string]interface{}{"x": 1, "z": 3}
+	got, err = MarshalJSONMergePatch(c.Patch(a, b), a, b)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"email": null,
+		"address": {"city": "Munich"},
+		"tags": ["a", "c"],
+		"extra": {"drop": null, "obj": {"y": null, "z": 3}, "new": true}
+	}`, string(got))
+
+	got, err = MarshalJSONMergePatch(c.Patch(a, a), a, a)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{}`, string(got))
+
+	got, err = MarshalJSONMergePatch(c.Patch([]int{1}, []int{2}), []int{1}, []int{2})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[2]`, string(got))
+}
@@ -0,0 +1,208 @@
+package pretty
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// JSONPatchOperation is an operation of an RFC 6902 JSON Patch document.
+type JSONPatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// JSONPatch converts patch, computed for values of the type of root, to
+// an RFC 6902 JSON Patch with JSON Pointer paths. Struct fields are named
+// by their json tags and embedded structs are flattened as encoding/json
+// does. Operations on fields that encoding/json skips are left out. Behind
+// interfaces the static type is unknown, so Go field names are used there.
+//
+// A set becomes "replace" for array elements and the whole document, and
+// "add" for object members, which may be missing from the document, for
+// example if they are tagged omitempty. A set of a field tagged omitempty
+// to an empty value becomes "remove", as encoding/json leaves it out.
+//
+// Patch rather than StructuredDiff is the input, because the slice
+// indices of a JSON Patch must refer to the document as left by the
+// preceding operations.
+func JSONPatch(patch Patch, root interface{}) ([]JSONPatchOperation, error) {
+	t := reflect.TypeOf(root)
+	ops := make([]JSONPatchOperation, 0, len(patch))
+	for _, o := range patch {
+		keys, omitEmpty, ok := jsonKeys(o.Path, t)
+		if !ok {
+			continue
+		}
+		op := JSONPatchOperation{Path: jsonPointer(keys)}
+		switch o.Op {
+		case PatchSet:
+			op.Op = "add"
+			if s, ok := lastStep(o.Path); !ok || s.Kind == IndexStep {
+				op.Op = "replace"
+			} else if omitEmpty && isEmptyJSONValue(reflect.ValueOf(o.Value)) {
+				op.Op = "remove"
+			}
+		case PatchInsert, PatchAddKey:
+			op.Op = "add"
+		case PatchRemove, PatchDeleteKey:
+			op.Op = "remove"
+		default:
+			return nil, fmt.Errorf("pretty: unknown patch operation %v", o.Op)
+		}
+		if op.Op != "remove" {
+			value, err := json.Marshal(o.Value)
+			if err != nil {
+				return nil, fmt.Errorf("pretty: cannot encode value of %v: %v", o, err)
+			}
+			op.Value = value
+		}
+		ops = append(ops, op)
+	}
+	return ops, nil
+}
+
+// MarshalJSONPatch returns the JSON Patch document of patch, see JSONPatch.
+func MarshalJSONPatch(patch Patch, root interface{}) ([]byte, error) {
+	ops, err := JSONPatch(patch, root)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(ops)
+}
+
+// lastStep returns the last step of path that selects a part of a value,
+// or false if path selects the root value.
+func lastStep(path Path) (PathStep, bool) {
+	for i := len(path) - 1; i >= 0; i-- {
+		if s := path[i]; s.Kind != DerefStep && s.Kind != UnwrapStep {
+			return s, true
+		}
+	}
+	return PathStep{}, false
+}
+
+// jsonPointer returns the JSON Pointer made of keys.
+func jsonPointer(keys []string) string {
+	var b strings.Builder
+	for _, k := range keys {
+		b.WriteString("/" + escapeJSONPointer(k))
+	}
+	return b.String()
+}
+
+// jsonKeys returns the object keys and array indices leading to the
+// value at path in the JSON encoding of values of type t, or false if
+// encoding/json does not encode the value at path. omitEmpty tells
+// whether the value is a struct field tagged omitempty.
+func jsonKeys(path Path, t reflect.Type) (keys []string, omitEmpty, ok bool) {
+	keys = make([]string, 0, len(path))
+	for _, s := range path {
+		for t != nil && t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		omitEmpty = false
+		switch s.Kind {
+		case FieldStep:
+			var name string
+			if t != nil && t.Kind() == reflect.Struct {
+				f, ok := t.FieldByName(s.Name)
+				if !ok {
+					return nil, false, false
+				}
+				var encoded, inline bool
+				if name, encoded, inline, omitEmpty = jsonFieldName(f); !encoded {
+					return nil, false, false
+				}
+				t = f.Type
+				if inline {
+					continue
+				}
+			} else {
+				name, t = s.Name, nil
+			}
+			keys = append(keys, name)
+		case IndexStep:
+			keys = append(keys, strconv.Itoa(s.Index))
+			t = elemType(t)
+		case KeyStep:
+			keys = append(keys, fmt.Sprint(s.Key))
+			t = elemType(t)
+		case UnwrapStep:
+			t = nil
+		}
+	}
+	return keys, omitEmpty, true
+}
+
+// jsonFieldName returns the name encoding/json gives to f, whether it
+// encodes f at all, whether it inlines the fields of f instead and
+// whether it omits f if empty.
+func jsonFieldName(f reflect.StructField) (name string, encoded, inline, omitEmpty bool) {
+	tag := f.Tag.Get("json")
+	if tag == "-" {
+		return "", false, false, false
+	}
+	tag, opts, _ := strings.Cut(tag, ",")
+	for _, opt := range strings.Split(opts, ",") {
+		omitEmpty = omitEmpty || opt == "omitempty"
+	}
+	if f.Anonymous && tag == "" {
+		t := f.Type
+		if t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if t.Kind() == reflect.Struct {
+			return "", true, true, false
+		}
+	}
+	if f.PkgPath != "" {
+		return "", false, false, false
+	}
+	if tag == "" {
+		tag = f.Name
+	}
+	return tag, true, false, omitEmpty
+}
+
+// isEmptyJSONValue reports whether encoding/json considers v empty for
+// omitempty.
+func isEmptyJSONValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Invalid:
+		return true
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
+
+func elemType(t reflect.Type) reflect.Type {
+	if t == nil {
+		return nil
+	}
+	switch t.Kind() {
+	case reflect.Array, reflect.Slice, reflect.Map:
+		return t.Elem()
+	}
+	return nil
+}
+
+var jsonPointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+func escapeJSONPointer(s string) string {
+	return jsonPointerEscaper.Replace(s)
+}
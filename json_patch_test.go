@@ -0,0 +1,189 @@
+package pretty
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type jsonPatchAudit struct {
+	Version int `json:"version"`
+}
+
+type jsonPatchDoc struct {
+	jsonPatchAudit
+	Name   string            `json:"name,omitempty"`
+	Tags   []string          `json:"tags"`
+	Attrs  map[string]string `json:"attrs"`
+	Secret string            `json:"-"`
+	Plain  int
+	Extra  map[string]interface{} `json:"extra"`
+}
+
+func TestJSONPatch(t *testing.T) {
+	a := jsonPatchDoc{
+		jsonPatchAudit: jsonPatchAudit{Version: 1},
+		Name:           "a",
+		Tags:           []string{"x", "y"},
+		Attrs:          map[string]string{"a/b": "1", "gone": "2"},
+		Secret:         "s",
+		Extra:          map[string]interface{}{"nested": jsonPatchAudit{Version: 1}},
+	}
+	b := jsonPatchDoc{
+		jsonPatchAudit: jsonPatchAudit{Version: 2},
+		Name:           "b",
+		Tags:           []string{"y", "z"},
+		Attrs:          map[string]string{"a/b": "3", "new~": "4"},
+		Secret:         "t",
+		Plain:          5,
+		Extra:          map[string]interface{}{"nested": jsonPatchAudit{Version: 2}},
+	}
+	patch := NewCustomDiff().Patch(a, b)
+
+	got, err := MarshalJSONPatch(patch, a)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[
+		{"op": "add", "path": "/version", "value": 2},
+		{"op": "add", "path": "/name", "value": "b"},
+		{"op": "remove", "path": "/tags/0"},
+		{"op": "add", "path": "/tags/1", "value": "z"},
+		{"op": "remove", "path": "/attrs/gone"},
+		{"op": "add", "path": "/attrs/a~1b", "value": "3"},
+		{"op": "add", "path": "/attrs/new~0", "value": "4"},
+		{"op": "add", "path": "/Plain", "value": 5},
+		{"op": "add", "path": "/extra/nested/Version", "value": 2}
+	]`, string(got))
+
+	got, err = MarshalJSONPatch(NewCustomDiff().Patch(jsonPatchDoc{Tags: []string{"x"}}, jsonPatchDoc{Name: "b", Tags: []string{"y"}}), a)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[
+		{"op": "add", "path": "/name", "value": "b"},
+		{"op": "replace", "path": "/tags/0", "value": "y"}
+	]`, string(got), "the omitted name is added, the existing element replaced")
+
+	ops, err := JSONPatch(Patch{{Op: PatchSet, Value: nil}}, &a)
+	assert.NoError(t, err)
+	assert.Equal(t, []JSONPatchOperation{{Op: "replace", Path: "", Value: []byte("null")}}, ops)
+
+	_, err = JSONPatch(Patch{{Op: PatchSet, Value: func() {}}}, a)
+	assert.EqualError(t, err, "pretty: cannot encode value of set  = func() {...}: json: unsupported type: func()")
+}
+
+// applyJSONPatch applies ops to the JSON document doc as RFC 6902
+// requires, failing t for operations whose target or parent is missing.
+func applyJSONPatch(t *testing.T, doc interface{}, ops []JSONPatchOperation) interface{} {
+	t.Helper()
+	for _, op := range ops {
+		var value interface{}
+		if op.Op != "remove" {
+			assert.NoError(t, json.Unmarshal(op.Value, &value))
+		}
+		var tokens []string
+		if op.Path != "" {
+			tokens = strings.Split(op.Path, "/")[1:]
+		}
+		for i, tok := range tokens {
+			tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(tok)
+		}
+		doc = applyJSONPatchOp(t, doc, tokens, op, value)
+	}
+	return doc
+}
+
+func applyJSONPatchOp(t *testing.T, doc interface{}, tokens []string, op JSONPatchOperation, value interface{}) interface{} {
+	t.Helper()
+	if len(tokens) == 0 {
+		return value
+	}
+	switch c := doc.(type) {
+	case map[string]interface{}:
+		k := tokens[0]
+		old, ok := c[k]
+		switch {
+		case len(tokens) > 1:
+			if !ok {
+				t.Fatalf("%s %s: no member %q", op.Op, op.Path, k)
+			}
+			c[k] = applyJSONPatchOp(t, old, tokens[1:], op, value)
+		case op.Op == "add":
+			c[k] = value
+		case !ok:
+			t.Fatalf("%s %s: no member %q", op.Op, op.Path, k)
+		case op.Op == "replace":
+			c[k] = value
+		default:
+			delete(c, k)
+		}
+		return c
+	case []interface{}:
+		i, err := strconv.Atoi(tokens[0])
+		if err != nil || i < 0 || i > len(c) || i == len(c) && (len(tokens) > 1 || op.Op != "add") {
+			t.Fatalf("%s %s: no element %s", op.Op, op.Path, tokens[0])
+		}
+		switch {
+		case len(tokens) > 1:
+			c[i] = applyJSONPatchOp(t, c[i], tokens[1:], op, value)
+		case op.Op == "add":
+			c = append(c[:i], append([]interface{}{value}, c[i:]...)...)
+		case op.Op == "replace":
+			c[i] = value
+		default:
+			c = append(c[:i], c[i+1:]...)
+		}
+		return c
+	}
+	t.Fatalf("%s %s: parent is %v", op.Op, op.Path, doc)
+	return nil
+}
+
+func TestJSONPatchApplies(t *testing.T) {
+	type item struct {
+		SKU string `json:"sku"`
+		Qty int    `json:"qty,omitempty"`
+	}
+	type doc struct {
+		Name  string            `json:"name,omitempty"`
+		M     map[string]int    `json:"m"`
+		Vals  []int             `json:"vals"`
+		Items []item            `json:"items,omitempty"`
+		Ptr   *item             `json:"ptr,omitempty"`
+		Attrs map[string]string `json:"attrs,omitempty"`
+	}
+	tests := []struct {
+		name string
+		a, b doc
+	}{
+		{"nil containers gain elements", doc{}, doc{M: map[string]int{"k": 1}, Vals: []int{7}}},
+		{"containers become nil", doc{M: map[string]int{"k": 1}, Vals: []int{7}}, doc{}},
+		{"omitempty set to empty", doc{Name: "a", Attrs: map[string]string{"x": "1"}}, doc{Attrs: map[string]string{}}},
+		{"omitempty empty container gains elements", doc{Attrs: map[string]string{}, Items: []item{}}, doc{Attrs: map[string]string{"x": "1"}, Items: []item{{SKU: "s"}}}},
+		{"omitempty set from empty", doc{}, doc{Name: "b", Items: []item{{SKU: "s"}}, Ptr: &item{SKU: "p"}}},
+		{"nested", doc{Items: []item{{"a", 1}, {"b", 2}}, Ptr: &item{Qty: 1}}, doc{Items: []item{{"b", 0}, {"c", 3}}, Ptr: &item{SKU: "p"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ops, err := JSONPatch(NewCustomDiff().Patch(tt.a, tt.b), tt.a)
+			assert.NoError(t, err)
+			aDoc, err := jsonDocument(tt.a)
+			assert.NoError(t, err)
+			bJSON, err := json.Marshal(tt.b)
+			assert.NoError(t, err)
+			got, err := json.Marshal(applyJSONPatch(t, aDoc, ops))
+			assert.NoError(t, err)
+			assert.JSONEq(t, string(bJSON), string(got))
+		})
+	}
+
+	ops, err := JSONPatch(NewCustomDiff().Patch(doc{}, doc{M: map[string]int{"k": 1}, Vals: []int{7}}), doc{})
+	assert.NoError(t, err)
+	assert.Equal(t, []JSONPatchOperation{
+		{Op: "add", Path: "/m", Value: []byte(`{"k":1}`)},
+		{Op: "add", Path: "/vals", Value: []byte(`[7]`)},
+	}, ops)
+	ops, err = JSONPatch(NewCustomDiff().Patch(doc{Name: "a"}, doc{}), doc{})
+	assert.NoError(t, err)
+	assert.Equal(t, []JSONPatchOperation{{Op: "remove", Path: "/name"}}, ops)
+}
@@ -1,7 +1,6 @@
 package pretty
 
 import (
-	"strings"
 	"sync"
 )
 
@@ -48,10 +47,16 @@ func (l *labels) Current(currentLevel string) []Label {
 	for i, lab := range l.labelNames {
 		result[i].Name = lab
 	}
-	breadCrumbs := strings.Split(currentLevel, sep)
-
-	for depth := 1; depth <= len(breadCrumbs); depth++ {
-		level := strings.Join(breadCrumbs[:depth], sep)
+	// Visit the levels from the top level value, whose path is empty,
+	// down to currentLevel.
+	var level string
+	for depth, elems := 0, splitPath(currentLevel); depth <= len(elems); depth++ {
+		if depth > 0 {
+			if depth > 1 && elems[depth-1][0] != '[' {
+				level += sep
+			}
+			level += elems[depth-1]
+		}
 		if l.levelsLabelsMap[level] != nil {
 			for i, name := range l.labelNames {
 				val, ok := l.levelsLabelsMap[level][name]
@@ -69,6 +74,22 @@ func (l *labels) Current(currentLevel string) []Label {
 	return result
 }
 
+// labelRegisterer is implemented by Labels that accept label names
+// found while diffing, such as fields tagged `pretty:"label"`.
+type labelRegisterer interface {
+	register(name string)
+}
+
+func (l *labels) register(name string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.labelNamesMap[name]; ok {
+		return
+	}
+	l.labelNamesMap[name] = struct{}{}
+	l.labelNames = append(l.labelNames, name)
+}
+
 func NewLabels(names ...string) Labels {
 	m := make(map[string]struct{})
 	for _, n := range names {
@@ -78,6 +99,8 @@ func NewLabels(names ...string) Labels {
 		mu:              sync.RWMutex{},
 		levelsLabelsMap: make(map[string]map[string]string),
 		labelNamesMap:   m,
-		labelNames:      names,
+		// register appends to labelNames, which must not write to the
+		// caller's array, such as the names shared by a Comparator.
+		labelNames: append([]string(nil), names...),
 	}
 }
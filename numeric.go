@@ -0,0 +1,108 @@
+package pretty
+
+import "math"
+
+// Tolerance describes how far apart two numbers may be and still be equal.
+// Numbers are equal if they satisfy any of the non-zero criteria. The zero
+// Tolerance only allows the precision of the Comparator (see WithPrecision),
+// which is added to every absolute tolerance to absorb rounding; with
+// WithPrecision(0) it compares numbers exactly.
+type Tolerance struct {
+	// Absolute is the maximum of |a-b|, to which the precision is added.
+	Absolute float64
+	// Relative is the maximum of |a-b| / max(|a|,|b|).
+	Relative float64
+	// ULPs is the maximum number of representable float64 values between a and b.
+	ULPs uint64
+}
+
+// comparator returns the equality function for t. precision is added to
+// the absolute tolerance to absorb floating point rounding.
+func (t Tolerance) comparator(precision float64) Float64Equals {
+	var equals []Float64Equals
+	if t.Absolute != 0 || t.Relative == 0 && t.ULPs == 0 {
+		equals = append(equals, newMustAbsoluteDeltaLessThan(t.Absolute, precision))
+	}
+	if t.Relative != 0 {
+		equals = append(equals, newMustRelativeDeltaLessThan(t.Relative))
+	}
+	if t.ULPs != 0 {
+		equals = append(equals, newMustULPDistanceLessThan(t.ULPs))
+	}
+	return anyFloat64Equals(equals...)
+}
+
+// pathTolerance applies a tolerance to numbers at paths matching pattern.
+type pathTolerance struct {
+	pattern   pathPattern
+	tolerance Tolerance
+}
+
+// numericRule is a pathTolerance resolved for the precision of a Comparator.
+type numericRule struct {
+	pattern pathPattern
+	equals  Float64Equals
+}
+
+func newMustRelativeDeltaLessThan(r float64) func(a, b float64) bool {
+	return func(a, b float64) bool {
+		return a == b || math.Abs(a-b) <= r*math.Max(math.Abs(a), math.Abs(b))
+	}
+}
+
+func newMustULPDistanceLessThan(ulps uint64) func(a, b float64) bool {
+	return func(a, b float64) bool {
+		if math.IsNaN(a) || math.IsNaN(b) {
+			return false
+		}
+		return a == b || ulpDistance(a, b) <= ulps
+	}
+}
+
+// anyFloat64Equals reports a and b as equal if any of equals does.
+func anyFloat64Equals(equals ...Float64Equals) Float64Equals {
+	if len(equals) == 1 {
+		return equals[0]
+	}
+	return func(a, b float64) bool {
+		for _, eq := range equals {
+			if eq(a, b) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// ulpDistance returns the number of representable float64 values
+// between a and b.
+func ulpDistance(a, b float64) uint64 {
+	ia, ib := orderedBits(a), orderedBits(b)
+	if ia < ib {
+		ia, ib = ib, ia
+	}
+	return uint64(ia) - uint64(ib)
+}
+
+// orderedBits maps f to an int64 such that the order of the results
+// matches the order of the floats, with -0 and +0 both mapped to 0.
+func orderedBits(f float64) int64 {
+	b := int64(math.Float64bits(f))
+	if b < 0 {
+		return math.MinInt64 - b
+	}
+	return b
+}
+
+func absoluteDelta(a, b float64) float64 {
+	return math.Abs(a - b)
+}
+
+// relativeDelta returns |a-b| / max(|a|,|b|), the measure of
+// Tolerance.Relative, which is 0 if a and b are both 0.
+func relativeDelta(a, b float64) float64 {
+	if a == b {
+		return 0
+	}
+	return math.Abs(a-b) / math.Max(math.Abs(a), math.Abs(b))
+}
@@ -0,0 +1,78 @@
+package pretty
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNumericComparators(t *testing.T) {
+	tests := []struct {
+		name  string
+		equal Float64Equals
+		a, b  float64
+		want  bool
+	}{
+		{"relative small", newMustRelativeDeltaLessThan(1e-3), 1e-9, 1.0005e-9, true},
+		{"relative small differs", newMustRelativeDeltaLessThan(1e-3), 1e-9, 1.002e-9, false},
+		{"relative large", newMustRelativeDeltaLessThan(1e-3), 1e12, 1.0005e12, true},
+		{"relative zero", newMustRelativeDeltaLessThan(1e-3), 0, 1e-300, false},
+		{"relative inf", newMustRelativeDeltaLessThan(1e-3), math.Inf(1), math.Inf(1), true},
+		{"abs or rel near zero", Tolerance{Absolute: 1e-6, Relative: 1e-3}.comparator(defaultPrecision), 0, 1e-7, true},
+		{"abs or rel large", Tolerance{Absolute: 1e-6, Relative: 1e-3}.comparator(defaultPrecision), 1e12, 1.0005e12, true},
+		{"abs or rel differs", Tolerance{Absolute: 1e-6, Relative: 1e-3}.comparator(defaultPrecision), 1, 1.01, false},
+		{"ulps next", newMustULPDistanceLessThan(1), 1, math.Nextafter(1, 2), true},
+		{"ulps two", newMustULPDistanceLessThan(1), 1, math.Nextafter(math.Nextafter(1, 2), 2), false},
+		{"ulps across zero", newMustULPDistanceLessThan(2), math.Nextafter(0, -1), math.Nextafter(0, 1), true},
+		{"ulps signed zero", newMustULPDistanceLessThan(0), math.Copysign(0, -1), 0, true},
+		{"ulps nan", newMustULPDistanceLessThan(100), math.NaN(), math.NaN(), false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, tt.equal(tt.a, tt.b), tt.name)
+	}
+}
+
+func TestULPDistance(t *testing.T) {
+	assert.Equal(t, uint64(0), ulpDistance(1, 1))
+	assert.Equal(t, uint64(1), ulpDistance(1, math.Nextafter(1, 0)))
+	assert.Equal(t, uint64(2), ulpDistance(-math.SmallestNonzeroFloat64, math.SmallestNonzeroFloat64))
+	assert.Equal(t, ulpDistance(-1, 1), ulpDistance(1, -1))
+}
+
+func TestStructuredNumericDelta(t *testing.T) {
+	type price float32
+	type value struct {
+		Price price
+		Qty   uint8
+		Name  string
+	}
+	a := value{Price: 4, Qty: 3, Name: "a"}
+	b := value{Price: 5, Qty: 1, Name: "b"}
+	tests := []struct {
+		name string
+		opts []func(*Options)
+	}{
+		{"exact", nil},
+		{"tolerance", []func(*Options){WithNumericEpsilon(0.5)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _ := NewCustomDiff(tt.opts...).StructuredDiff(a, b)
+			var deltas []*NumericDelta
+			for _, d := range got {
+				deltas = append(deltas, d.Numeric)
+			}
+			assert.Equal(t, []*NumericDelta{
+				{A: 4, B: 5, Absolute: 1, Relative: 0.2},
+				{A: 3, B: 1, Absolute: 2, Relative: 2.0 / 3},
+				nil,
+			}, deltas)
+		})
+	}
+}
+
+func TestZeroTolerance(t *testing.T) {
+	assert.True(t, Tolerance{}.comparator(defaultPrecision)(1, 1+defaultPrecision/2), "within the precision")
+	assert.False(t, Tolerance{}.comparator(0)(1, math.Nextafter(1, 2)), "exact without precision")
+}
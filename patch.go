@@ -0,0 +1,372 @@
+package pretty
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// PatchOp is the kind of a PatchOperation.
+type PatchOp int
+
+const (
+	// PatchSet replaces the value at Path with Value.
+	PatchSet PatchOp = iota
+	// PatchInsert inserts Value into a slice before the element at Path.
+	PatchInsert
+	// PatchRemove removes the slice element at Path.
+	PatchRemove
+	// PatchAddKey stores Value in a map under the key at Path.
+	PatchAddKey
+	// PatchDeleteKey deletes the key at Path from a map.
+	PatchDeleteKey
+)
+
+func (op PatchOp) String() string {
+	switch op {
+	case PatchSet:
+		return "set"
+	case PatchInsert:
+		return "insert"
+	case PatchRemove:
+		return "remove"
+	case PatchAddKey:
+		return "add key"
+	case PatchDeleteKey:
+		return "delete key"
+	}
+	return fmt.Sprintf("PatchOp(%d)", int(op))
+}
+
+// PatchOperation is a single change to a value. Paths step through
+// pointers and interfaces implicitly.
+type PatchOperation struct {
+	Op    PatchOp
+	Path  Path
+	Value interface{}
+}
+
+func (o PatchOperation) String() string {
+	switch o.Op {
+	case PatchRemove, PatchDeleteKey:
+		return fmt.Sprintf("%v %v", o.Op, o.Path)
+	}
+	return fmt.Sprintf("%v %v = %# v", o.Op, o.Path, Formatter(o.Value))
+}
+
+// Patch is a sequence of operations turning one value into another.
+// The operations must be applied in order: slice indices refer to the
+// slice as left by the preceding operations.
+type Patch []PatchOperation
+
+// Apply applies patch to the value target points to. Values are stored
+// as they are, not copied, so after Apply target may share memory with
+// the value the patch was computed from. Apply stops at the first
+// operation that does not fit target and returns an error describing it.
+func Apply(patch Patch, target interface{}) error {
+	v := reflect.ValueOf(target)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return fmt.Errorf("pretty: Apply target must be a non-nil pointer, got %T", target)
+	}
+	for _, op := range patch {
+		if err := applyOperation(v.Elem(), op.Path, op); err != nil {
+			return fmt.Errorf("pretty: cannot %v: %v", op, err)
+		}
+	}
+	return nil
+}
+
+// applyOperation applies op to the settable value v, of which path is
+// the part of op.Path not walked yet.
+func applyOperation(v reflect.Value, path Path, op PatchOperation) error {
+	if len(path) == 0 {
+		if op.Op != PatchSet {
+			return fmt.Errorf("%v needs a slice index or map key", op.Op)
+		}
+		x, err := patchValue(op.Value, v.Type())
+		if err != nil {
+			if v.Kind() == reflect.Ptr && !v.IsNil() {
+				return applyOperation(v.Elem(), path, op)
+			}
+			return err
+		}
+		v.Set(x)
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return fmt.Errorf("nil pointer before %v", path)
+		}
+		return applyOperation(v.Elem(), path, op)
+	case reflect.Interface:
+		if v.IsNil() {
+			return fmt.Errorf("nil interface before %v", path)
+		}
+		// The value held by an interface cannot be modified in place.
+		c := reflect.New(v.Elem().Type()).Elem()
+		c.Set(v.Elem())
+		if err := applyOperation(c, path, op); err != nil {
+			return err
+		}
+		v.Set(c)
+		return nil
+	}
+
+	step, last := path[0], len(path) == 1
+	switch step.Kind {
+	case DerefStep, UnwrapStep:
+		// Pointers and interfaces were passed through above.
+		return applyOperation(v, path[1:], op)
+	case FieldStep:
+		if v.Kind() != reflect.Struct {
+			return fmt.Errorf("field %s of %v", step.Name, v.Type())
+		}
+		f := v.FieldByName(step.Name)
+		if !f.IsValid() {
+			return fmt.Errorf("%v has no field %s", v.Type(), step.Name)
+		}
+		if !f.CanSet() {
+			return fmt.Errorf("field %s of %v is unexported", step.Name, v.Type())
+		}
+		return applyOperation(f, path[1:], op)
+	case IndexStep:
+		if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+			return fmt.Errorf("index %d of %v", step.Index, v.Type())
+		}
+		i, n := step.Index, v.Len()
+		if last && op.Op == PatchInsert {
+			if v.Kind() != reflect.Slice || i < 0 || i > n {
+				return fmt.Errorf("insert at %d into %v of length %d", i, v.Type(), n)
+			}
+			x, err := patchValue(op.Value, v.Type().Elem())
+			if err != nil {
+				return err
+			}
+			v.Set(reflect.Append(v, x))
+			reflect.Copy(v.Slice(i+1, n+1), v.Slice(i, n))
+			v.Index(i).Set(x)
+			return nil
+		}
+		if i < 0 || i >= n {
+			return fmt.Errorf("index %d out of range of %v of length %d", i, v.Type(), n)
+		}
+		if last && op.Op == PatchRemove {
+			if v.Kind() != reflect.Slice {
+				return fmt.Errorf("remove from %v", v.Type())
+			}
+			reflect.Copy(v.Slice(i, n), v.Slice(i+1, n))
+			v.Index(n - 1).Set(reflect.Zero(v.Type().Elem()))
+			v.Set(v.Slice(0, n-1))
+			return nil
+		}
+		return applyOperation(v.Index(i), path[1:], op)
+	case KeyStep:
+		if v.Kind() != reflect.Map {
+			return fmt.Errorf("key %#v of %v", step.Key, v.Type())
+		}
+		k, err := patchValue(step.Key, v.Type().Key())
+		if err != nil {
+			return err
+		}
+		if last && op.Op == PatchAddKey {
+			x, err := patchValue(op.Value, v.Type().Elem())
+			if err != nil {
+				return err
+			}
+			if v.IsNil() {
+				v.Set(reflect.MakeMap(v.Type()))
+			}
+			v.SetMapIndex(k, x)
+			return nil
+		}
+		if last && op.Op == PatchDeleteKey {
+			v.SetMapIndex(k, reflect.Value{})
+			return nil
+		}
+		e := v.MapIndex(k)
+		if !e.IsValid() {
+			return fmt.Errorf("no key %#v in %v", step.Key, v.Type())
+		}
+		// Map values are not addressable, so modify a copy and store it back.
+		c := reflect.New(e.Type()).Elem()
+		c.Set(e)
+		if err := applyOperation(c, path[1:], op); err != nil {
+			return err
+		}
+		v.SetMapIndex(k, c)
+		return nil
+	}
+	return fmt.Errorf("unknown step %v", step)
+}
+
+// patchValue returns x as a value assignable to t.
+func patchValue(x interface{}, t reflect.Type) (reflect.Value, error) {
+	if x == nil {
+		switch t.Kind() {
+		case reflect.Chan, reflect.Func, reflect.Interface, reflect.Map, reflect.Ptr, reflect.Slice:
+			return reflect.Zero(t), nil
+		}
+		return reflect.Value{}, fmt.Errorf("nil is not a %v", t)
+	}
+	v := reflect.ValueOf(x)
+	switch {
+	case v.Type().AssignableTo(t):
+		return v, nil
+	case v.Type().ConvertibleTo(t):
+		return v.Convert(t), nil
+	}
+	return reflect.Value{}, fmt.Errorf("%v is not a %v", v.Type(), t)
+}
+
+// patch appends to ops the operations turning av into bv, which are at
+// path. It descends into containers of the same type and replaces
+// everything else the Comparator finds different as a whole.
+func (w diffPrinter) patch(ops *Patch, path Path, av, bv reflect.Value) {
+	if w.ignored() {
+		return
+	}
+	if av.IsValid() && bv.IsValid() && av.Type() == bv.Type() {
+		p := w.plan(av.Type())
+		if !p.time && p.custom == nil && w.patchContainer(ops, path, p, av, bv) {
+			return
+		}
+	}
+	if !w.equal(av, bv) {
+		*ops = append(*ops, PatchOperation{Op: PatchSet, Path: path, Value: interfaceOf(bv)})
+	}
+}
+
+// patchContainer appends the operations for the elements of av and bv
+// if they are containers, and reports whether they were.
+func (w diffPrinter) patchContainer(ops *Patch, path Path, p *typePlan, av, bv reflect.Value) bool {
+	switch av.Kind() {
+	case reflect.Ptr:
+		if av.IsNil() || bv.IsNil() {
+			return false
+		}
+		// Leave cycles to equal, which detects them.
+		vis := visit{av.Pointer(), av.Type()}
+		if _, ok := w.aVisited[vis]; ok {
+			return false
+		}
+		w.aVisited[vis] = visit{bv.Pointer(), bv.Type()}
+		w.patch(ops, path, av.Elem(), bv.Elem())
+	case reflect.Interface:
+		if av.IsNil() || bv.IsNil() || av.Elem().Type() != bv.Elem().Type() {
+			return false
+		}
+		w.patch(ops, path, av.Elem(), bv.Elem())
+	case reflect.Struct:
+		for i, f := range p.fields {
+			if f.ignore {
+				continue
+			}
+			fw := w.relabel(f.name)
+			if f.numeric != nil {
+				fw.tagNumeric = f.numeric
+			}
+			fw.unorderedTag = f.unordered
+			fw.patch(ops, path.with(fieldStep(f.name)), av.Field(i), bv.Field(i))
+		}
+	case reflect.Array:
+		for i := 0; i < av.Len(); i++ {
+			w.relabel(indexLabel(i)).patch(ops, path.with(indexStep(i)), av.Index(i), bv.Index(i))
+		}
+	case reflect.Map:
+		// A map or slice that is or becomes nil or empty is set as a
+		// whole, as its JSON encoding, null or nothing for omitempty,
+		// has no elements to add to or remove from.
+		if !sameEmptiness(av, bv) {
+			return false
+		}
+		ak, both, bk := keyDiff(av.MapKeys(), bv.MapKeys())
+		for _, k := range ak {
+			w := w.relabel(fmt.Sprintf("[%#v]", k))
+			if !w.ignored() {
+				*ops = append(*ops, PatchOperation{Op: PatchDeleteKey, Path: path.with(keyStep(interfaceOf(k)))})
+			}
+		}
+		for _, k := range both {
+			w.relabel(fmt.Sprintf("[%#v]", k)).patch(ops, path.with(keyStep(interfaceOf(k))), av.MapIndex(k), bv.MapIndex(k))
+		}
+		for _, k := range bk {
+			w := w.relabel(fmt.Sprintf("[%#v]", k))
+			if !w.ignored() {
+				*ops = append(*ops, PatchOperation{Op: PatchAddKey, Path: path.with(keyStep(interfaceOf(k))), Value: interfaceOf(bv.MapIndex(k))})
+			}
+		}
+	case reflect.Slice:
+		if !sameEmptiness(av, bv) {
+			return false
+		}
+		if w.unorderedTag || p.unordered {
+			if w.equal(av, bv) {
+				return true
+			}
+		}
+		w.patchSlice(ops, path, p.sliceKey, av, bv)
+	default:
+		return false
+	}
+	return true
+}
+
+// sameEmptiness reports whether maps or slices av and bv are both nil,
+// both empty and not nil or both have elements.
+func sameEmptiness(av, bv reflect.Value) bool {
+	return av.IsNil() == bv.IsNil() && (av.Len() == 0) == (bv.Len() == 0)
+}
+
+// patchSlice appends the operations turning slice av into bv along an
+// edit script. Elements with equal keys, or equal elements if key is
+// nil, are kept, runs of removed and inserted elements are paired up
+// and patched in place and the rest is removed or inserted.
+func (w diffPrinter) patchSlice(ops *Patch, path Path, key sliceKeyFunc, av, bv reflect.Value) {
+	eq := func(i, j int) bool {
+		return keyEqual(key(av.Index(i)), key(bv.Index(j)))
+	}
+	maxWork := maxEditWork
+	if key == nil {
+		var hashed bool
+		if eq, hashed = w.elemEqual(av, bv); !hashed {
+			maxWork = maxEditWalks
+		}
+	}
+	// pos is the index in the slice being patched of the next element of av.
+	var pos int
+	var deleted, inserted []int
+	flush := func() {
+		n := len(deleted)
+		if len(inserted) < n {
+			n = len(inserted)
+		}
+		for k := 0; k < n; k++ {
+			w.relabel(indexLabel(deleted[k])).patch(ops, path.with(indexStep(pos)), av.Index(deleted[k]), bv.Index(inserted[k]))
+			pos++
+		}
+		for range deleted[n:] {
+			*ops = append(*ops, PatchOperation{Op: PatchRemove, Path: path.with(indexStep(pos))})
+		}
+		for _, j := range inserted[n:] {
+			*ops = append(*ops, PatchOperation{Op: PatchInsert, Path: path.with(indexStep(pos)), Value: interfaceOf(bv.Index(j))})
+			pos++
+		}
+		deleted, inserted = deleted[:0], inserted[:0]
+	}
+	for _, e := range editScript(av.Len(), bv.Len(), maxWork, -1, eq) {
+		switch e.op {
+		case editDelete:
+			deleted = append(deleted, e.a)
+		case editInsert:
+			inserted = append(inserted, e.b)
+		default:
+			flush()
+			if key != nil {
+				w.relabel(indexLabel(e.a)).patch(ops, path.with(indexStep(pos)), av.Index(e.a), bv.Index(e.b))
+			}
+			pos++
+		}
+	}
+	flush()
+}
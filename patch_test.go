@@ -0,0 +1,215 @@
+package pretty
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type patchLine struct {
+	SKU   string
+	Qty   int
+	Price float64
+}
+
+type patchOrder struct {
+	ID       int
+	Customer *string
+	Lines    []patchLine
+	Tags     []string
+	Meta     map[string]interface{}
+	Parent   *patchOrder
+	Note     interface{}
+}
+
+func Test_customDiffPrinter_Patch(t *testing.T) {
+	bob := "bob"
+	base := func() patchOrder {
+		alice := "alice"
+		return patchOrder{
+			ID:       1,
+			Customer: &alice,
+			Lines:    []patchLine{{"a", 1, 1.5}, {"b", 2, 2.5}, {"c", 3, 3.5}},
+			Tags:     []string{"x", "y", "z"},
+			Meta:     map[string]interface{}{"source": "web", "retries": 1},
+			Parent:   &patchOrder{ID: 10},
+			Note:     patchLine{SKU: "n"},
+		}
+	}
+	tests := []struct {
+		name   string
+		opts   []func(*Options)
+		change func(o *patchOrder)
+		want   Patch
+	}{
+		{
+			name:   "equal",
+			change: func(o *patchOrder) {},
+		},
+		{
+			name:   "field",
+			change: func(o *patchOrder) { o.ID = 2 },
+			want:   Patch{{Op: PatchSet, Path: Path{fieldStep("ID")}, Value: 2}},
+		},
+		{
+			name:   "through pointers",
+			change: func(o *patchOrder) { o.Customer = &bob; o.Parent = &patchOrder{ID: 11} },
+			want: Patch{
+				{Op: PatchSet, Path: Path{fieldStep("Customer")}, Value: "bob"},
+				{Op: PatchSet, Path: Path{fieldStep("Parent"), fieldStep("ID")}, Value: 11},
+			},
+		},
+		{
+			name:   "nil pointer",
+			change: func(o *patchOrder) { o.Customer = nil },
+			want:   Patch{{Op: PatchSet, Path: Path{fieldStep("Customer")}, Value: (*string)(nil)}},
+		},
+		{
+			name: "slice",
+			change: func(o *patchOrder) {
+				o.Tags = []string{"w", "x", "z", "q"}
+			},
+			want: Patch{
+				{Op: PatchInsert, Path: Path{fieldStep("Tags"), indexStep(0)}, Value: "w"},
+				{Op: PatchRemove, Path: Path{fieldStep("Tags"), indexStep(2)}},
+				{Op: PatchInsert, Path: Path{fieldStep("Tags"), indexStep(3)}, Value: "q"},
+			},
+		},
+		{
+			name: "slice element field",
+			change: func(o *patchOrder) {
+				o.Lines = append([]patchLine{{"0", 0, 0}}, o.Lines...)
+				o.Lines[2].Qty = 20
+			},
+			want: Patch{
+				{Op: PatchInsert, Path: Path{fieldStep("Lines"), indexStep(0)}, Value: patchLine{"0", 0, 0}},
+				{Op: PatchSet, Path: Path{fieldStep("Lines"), indexStep(2), fieldStep("Qty")}, Value: 20},
+			},
+		},
+		{
+			name: "keyed slice",
+			opts: []func(*Options){WithSliceKey(reflect.TypeOf(patchLine{}), "SKU")},
+			change: func(o *patchOrder) {
+				o.Lines = []patchLine{{"a", 1, 1.5}, {"c", 30, 3.5}}
+			},
+			want: Patch{
+				{Op: PatchRemove, Path: Path{fieldStep("Lines"), indexStep(1)}},
+				{Op: PatchSet, Path: Path{fieldStep("Lines"), indexStep(1), fieldStep("Qty")}, Value: 30},
+			},
+		},
+		{
+			name: "map",
+			change: func(o *patchOrder) {
+				o.Meta = map[string]interface{}{"source": "api", "user": "u"}
+			},
+			want: Patch{
+				{Op: PatchDeleteKey, Path: Path{fieldStep("Meta"), keyStep("retries")}},
+				{Op: PatchSet, Path: Path{fieldStep("Meta"), keyStep("source")}, Value: "api"},
+				{Op: PatchAddKey, Path: Path{fieldStep("Meta"), keyStep("user")}, Value: "u"},
+			},
+		},
+		{
+			name:   "interface",
+			change: func(o *patchOrder) { o.Note = patchLine{SKU: "m"} },
+			want:   Patch{{Op: PatchSet, Path: Path{fieldStep("Note"), fieldStep("SKU")}, Value: "m"}},
+		},
+		{
+			name:   "interface type",
+			change: func(o *patchOrder) { o.Note = "text" },
+			want:   Patch{{Op: PatchSet, Path: Path{fieldStep("Note")}, Value: "text"}},
+		},
+		{
+			name:   "tolerance",
+			opts:   []func(*Options){WithNumericEpsilon(0.1)},
+			change: func(o *patchOrder) { o.Lines[0].Price = 1.55; o.Lines[1].Price = 3 },
+			want:   Patch{{Op: PatchSet, Path: Path{fieldStep("Lines"), indexStep(1), fieldStep("Price")}, Value: 3.0}},
+		},
+		{
+			name:   "ignored",
+			opts:   []func(*Options){WithIgnorePaths("Meta", "Lines[*].Qty")},
+			change: func(o *patchOrder) { o.Meta = nil; o.Lines[0].Qty = 5 },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := base(), base()
+			tt.change(&b)
+			c := NewCustomDiff(tt.opts...)
+
+			got := c.Patch(a, b)
+			assert.Equal(t, tt.want, got)
+
+			target := base()
+			assert.NoError(t, Apply(got, &target))
+			assert.True(t, c.Equal(target, b), "%v", Diff(target, b))
+		})
+	}
+}
+
+func TestPatchRoundTrip(t *testing.T) {
+	type node struct {
+		Name     string
+		Children []*node
+		Attrs    map[int][]string
+	}
+	a := &node{Name: "root", Children: []*node{{Name: "a"}, {Name: "b", Attrs: map[int][]string{1: {"x"}}}}}
+	b := &node{Name: "root", Children: []*node{{Name: "b", Attrs: map[int][]string{1: {"x", "y"}, 2: nil}}, {Name: "c"}}}
+
+	patch := NewCustomDiff().Patch(a, b)
+	assert.NoError(t, Apply(patch, &a))
+	assert.Empty(t, Diff(a, b))
+}
+
+func TestApplyErrors(t *testing.T) {
+	type hidden struct {
+		n int
+	}
+	var s struct {
+		P *int
+		H hidden
+		L []int
+		M map[string]int
+	}
+	tests := []struct {
+		name   string
+		target interface{}
+		patch  Patch
+		want   string
+	}{
+		{
+			name:   "not a pointer",
+			target: s,
+			want:   "pretty: Apply target must be a non-nil pointer, got struct { P *int; H pretty.hidden; L []int; M map[string]int }",
+		},
+		{
+			name:   "nil pointer",
+			target: &s,
+			patch:  Patch{{Op: PatchSet, Path: Path{fieldStep("P"), fieldStep("X")}, Value: 1}},
+			want:   "pretty: cannot set P.X = int(1): nil pointer before X",
+		},
+		{
+			name:   "unexported",
+			target: &s,
+			patch:  Patch{{Op: PatchSet, Path: Path{fieldStep("H"), fieldStep("n")}, Value: 1}},
+			want:   "pretty: cannot set H.n = int(1): field n of pretty.hidden is unexported",
+		},
+		{
+			name:   "index",
+			target: &s,
+			patch:  Patch{{Op: PatchRemove, Path: Path{fieldStep("L"), indexStep(0)}}},
+			want:   "pretty: cannot remove L[0]: index 0 out of range of []int of length 0",
+		},
+		{
+			name:   "type",
+			target: &s,
+			patch:  Patch{{Op: PatchAddKey, Path: Path{fieldStep("M"), keyStep("k")}, Value: "v"}},
+			want:   "pretty: cannot add key M[\"k\"] = \"v\": string is not a int",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.EqualError(t, Apply(tt.patch, tt.target), tt.want)
+		})
+	}
+}
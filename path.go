@@ -0,0 +1,83 @@
+package pretty
+
+import (
+	"fmt"
+	"strings"
+)
+
+// StepKind tells which part of a value a PathStep selects.
+type StepKind int
+
+const (
+	// FieldStep selects the struct field PathStep.Name.
+	FieldStep StepKind = iota
+	// IndexStep selects the slice or array element PathStep.Index.
+	IndexStep
+	// KeyStep selects the map value stored under PathStep.Key.
+	KeyStep
+	// DerefStep selects the value a pointer points to.
+	DerefStep
+	// UnwrapStep selects the value held by an interface.
+	UnwrapStep
+)
+
+// PathStep is one step from a value to a value nested in it. Key holds
+// the map key itself, so keys that print alike remain distinguishable.
+type PathStep struct {
+	Kind  StepKind
+	Name  string
+	Index int
+	Key   interface{}
+}
+
+// String returns the step as it appears in StructuredDiff.FieldName,
+// which leaves out pointer and interface steps.
+func (s PathStep) String() string {
+	switch s.Kind {
+	case FieldStep:
+		return s.Name
+	case IndexStep:
+		return indexLabel(s.Index)
+	case KeyStep:
+		return fmt.Sprintf("[%#v]", s.Key)
+	}
+	return ""
+}
+
+// Path is a sequence of steps from a root value to a value nested in it.
+type Path []PathStep
+
+// String returns the path in the notation of StructuredDiff.FieldName, e.g. "Orders[3].Price".
+func (p Path) String() string {
+	var b strings.Builder
+	for _, s := range p {
+		if b.Len() > 0 && s.Kind == FieldStep {
+			b.WriteString(sep)
+		}
+		b.WriteString(s.String())
+	}
+	return b.String()
+}
+
+// with returns p extended by s. It never shares its backing array with p,
+// so paths of sibling values do not overwrite each other.
+func (p Path) with(s PathStep) Path {
+	return append(p[:len(p):len(p)], s)
+}
+
+func fieldStep(name string) PathStep {
+	return PathStep{Kind: FieldStep, Name: name}
+}
+
+func indexStep(i int) PathStep {
+	return PathStep{Kind: IndexStep, Index: i}
+}
+
+func keyStep(key interface{}) PathStep {
+	return PathStep{Kind: KeyStep, Key: key}
+}
+
+var (
+	derefStep  = PathStep{Kind: DerefStep}
+	unwrapStep = PathStep{Kind: UnwrapStep}
+)
@@ -0,0 +1,116 @@
+package pretty
+
+import "strings"
+
+// pathPattern is a compiled path pattern such as "Orders[*].UpdatedAt".
+// Each element is a field name or a bracketed index or map key, where
+// "*" matches any single element, "[*]" any index or key and "**" any
+// number of path elements.
+type pathPattern []string
+
+func compilePathPattern(pattern string) pathPattern {
+	return pathPattern(splitPath(pattern))
+}
+
+func compilePathPatterns(patterns []string) []pathPattern {
+	compiled := make([]pathPattern, len(patterns))
+	for i, p := range patterns {
+		compiled[i] = compilePathPattern(p)
+	}
+	return compiled
+}
+
+// splitPath splits a path as built by relabel into its field names and
+// bracketed elements. Brackets inside quoted map keys, or nested in map
+// keys such as [2]int{1, 2}, do not end an element.
+func splitPath(path string) []string {
+	var elems []string
+	for i := 0; i < len(path); {
+		switch path[i] {
+		case '.':
+			i++
+		case '[':
+			j := i + 1
+			var quote byte
+			var nested int
+		scan:
+			for ; j < len(path); j++ {
+				c := path[j]
+				if quote != 0 {
+					if c == '\\' {
+						j++
+					} else if c == quote {
+						quote = 0
+					}
+					continue
+				}
+				switch c {
+				case '"', '\'', '`':
+					quote = c
+				case '[':
+					nested++
+				case ']':
+					if nested == 0 {
+						break scan
+					}
+					nested--
+				}
+			}
+			if j < len(path) {
+				j++
+			}
+			elems = append(elems, path[i:j])
+			i = j
+		default:
+			j := strings.IndexAny(path[i:], ".[")
+			if j < 0 {
+				j = len(path) - i
+			}
+			elems = append(elems, path[i:i+j])
+			i += j
+		}
+	}
+	return elems
+}
+
+func (p pathPattern) match(path []string) bool {
+	if len(p) == 0 {
+		return len(path) == 0
+	}
+	if p[0] == "**" {
+		for i := 0; i <= len(path); i++ {
+			if p[1:].match(path[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(path) == 0 || !matchPathElem(p[0], path[0]) {
+		return false
+	}
+	return p[1:].match(path[1:])
+}
+
+func matchPathElem(pattern, elem string) bool {
+	switch pattern {
+	case "*":
+		return true
+	case "[*]":
+		return elem[0] == '['
+	}
+	return pattern == elem
+}
+
+// matchAny reports whether path matches one of patterns.
+func matchAny(patterns []pathPattern, path string) bool {
+	if len(patterns) == 0 || path == "" {
+		return false
+	}
+	elems := splitPath(path)
+	for _, p := range patterns {
+		if p.match(elems) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,49 @@
+package pretty
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{"", nil},
+		{"A", []string{"A"}},
+		{"A.B[3].C", []string{"A", "B", "[3]", "C"}},
+		{`[0]["a.b]c"].D`, []string{"[0]", `["a.b]c"]`, "D"}},
+		{`M["x\"]"]`, []string{"M", `["x\"]"]`}},
+		{`M[[2]int{1, 2}].N`, []string{"M", `[[2]int{1, 2}]`, "N"}},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, splitPath(tt.path), tt.path)
+	}
+}
+
+func TestPathPatternMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"Orders[*].UpdatedAt", "Orders[12].UpdatedAt", true},
+		{"Orders[*].UpdatedAt", "Orders[12].CreatedAt", false},
+		{"Orders[*].UpdatedAt", "Orders.UpdatedAt", false},
+		{"Meta.*", "Meta.Version", true},
+		{"Meta.*", "Meta", false},
+		{"Meta.*", "Meta[0]", true},
+		{"Meta.*", `Meta["host"]`, true},
+		{"Meta[*]", "Meta.Version", false},
+		{"**.UpdatedAt", "UpdatedAt", true},
+		{"**.UpdatedAt", "A[1].B.UpdatedAt", true},
+		{"**.UpdatedAt", "A[1].B.UpdatedAt.X", false},
+		{`M["a.b"]`, `M["a.b"]`, true},
+	}
+	for _, tt := range tests {
+		got := compilePathPattern(tt.pattern).match(splitPath(tt.path))
+		assert.Equal(t, tt.want, got, "%s ~ %s", tt.pattern, tt.path)
+	}
+}
@@ -0,0 +1,143 @@
+package pretty
+
+import (
+	"reflect"
+	"sync"
+)
+
+// typePlan holds the decisions diff makes for every value of a type that
+// depend only on the type and the Comparator options, so that they are
+// taken once per type rather than once per value.
+type typePlan struct {
+	time    bool
+	custom  Equals
+	numeric bool
+	integer bool
+	// exact is set if values of the type have no differences exactly if
+	// they are ==, so that they can be compared by hashing.
+	exact bool
+
+	// slices
+	sliceKey  sliceKeyFunc
+	unordered bool
+
+	// structs
+	fields      []fieldPlan
+	labelFields []int
+}
+
+type fieldPlan struct {
+	name      string
+	ignore    bool
+	label     bool
+	unordered bool
+	numeric   Float64Equals
+}
+
+// planCache maps a reflect.Type to its *typePlan.
+type planCache struct {
+	plans sync.Map
+}
+
+// plan returns the plan for values of type t under the options of c.
+func (c *diffConfig) plan(t reflect.Type) *typePlan {
+	if p, ok := c.plans.plans.Load(t); ok {
+		return p.(*typePlan)
+	}
+	p, _ := c.plans.plans.LoadOrStore(t, c.compile(t))
+	return p.(*typePlan)
+}
+
+func (c *diffConfig) compile(t reflect.Type) *typePlan {
+	p := &typePlan{
+		time:    t.ConvertibleTo(timeType),
+		custom:  c.customComparators[t],
+		numeric: t.ConvertibleTo(float64Type),
+		exact:   len(c.ignorePaths) == 0 && c.exact(t),
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		p.integer = true
+	case reflect.Slice:
+		p.sliceKey = c.sliceKey(t)
+		_, ok := c.unorderedSliceTypes[t]
+		p.unordered = c.unorderedSlices || ok
+	case reflect.Struct:
+		labelNames := make(map[string]bool, len(c.labelNames))
+		for _, name := range c.labelNames {
+			labelNames[name] = true
+		}
+		tags := structTags(t)
+		p.fields = make([]fieldPlan, t.NumField())
+		for i := range p.fields {
+			f := t.Field(i)
+			fp := &p.fields[i]
+			fp.name = f.Name
+			if tags != nil {
+				fp.ignore = tags[i].ignore
+				fp.label = tags[i].label
+				fp.unordered = tags[i].unordered
+				if tags[i].tolerance != nil {
+					fp.numeric = tags[i].tolerance.comparator(c.precision)
+				}
+			}
+			if f.Type.Kind() == reflect.String && (fp.label || labelNames[f.Name]) {
+				p.labelFields = append(p.labelFields, i)
+			}
+		}
+	}
+	return p
+}
+
+// sliceKey returns the key function registered for the element type of
+// slice type t, or for the type the elements point to.
+func (c *diffConfig) sliceKey(t reflect.Type) sliceKeyFunc {
+	if len(c.sliceKeys) == 0 {
+		return nil
+	}
+	et := t.Elem()
+	if key, ok := c.sliceKeys[et]; ok {
+		return key
+	}
+	if et.Kind() == reflect.Ptr {
+		return c.sliceKeys[et.Elem()]
+	}
+	return nil
+}
+
+// exact reports whether values of type t have no differences under the
+// options of c exactly if they are ==. Ignore patterns are left to the
+// caller.
+func (c *diffConfig) exact(t reflect.Type) bool {
+	if t.ConvertibleTo(timeType) || c.customComparators[t] != nil {
+		return false
+	}
+	if t.ConvertibleTo(float64Type) && (len(c.fieldNumeric) > 0 || len(c.pathNumeric) > 0) {
+		return false
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return c.numericComparator == nil || c.exactIntegers
+	case reflect.Float32, reflect.Float64:
+		return c.numericComparator == nil
+	case reflect.Bool, reflect.Complex64, reflect.Complex128, reflect.String:
+		return true
+	case reflect.Array:
+		return c.exact(t.Elem())
+	case reflect.Struct:
+		tags := structTags(t)
+		for i := 0; i < t.NumField(); i++ {
+			// == skips blank fields, diff does not.
+			if t.Field(i).Name == "_" || tags != nil && (tags[i].ignore || tags[i].tolerance != nil) {
+				return false
+			}
+			if !c.exact(t.Field(i).Type) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
@@ -11,6 +11,7 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"os"
 	"reflect"
 )
 
@@ -19,7 +20,7 @@ import (
 // Calling Errorf(f, x, y) is equivalent to
 // fmt.Errorf(f, Formatter(x), Formatter(y)).
 func Errorf(format string, a ...interface{}) error {
-	return fmt.Errorf(format, wrap(a, false)...)
+	return fmt.Errorf(format, wrap(a, false, false)...)
 }
 
 // Fprintf is a convenience wrapper for fmt.Fprintf.
@@ -27,7 +28,7 @@ func Errorf(format string, a ...interface{}) error {
 // Calling Fprintf(w, f, x, y) is equivalent to
 // fmt.Fprintf(w, f, Formatter(x), Formatter(y)).
 func Fprintf(w io.Writer, format string, a ...interface{}) (n int, error error) {
-	return fmt.Fprintf(w, format, wrap(a, false)...)
+	return fmt.Fprintf(w, format, wrap(a, false, useColor(w))...)
 }
 
 // Log is a convenience wrapper for log.Printf.
@@ -36,7 +37,7 @@ func Fprintf(w io.Writer, format string, a ...interface{}) (n int, error error)
 // log.Print(Formatter(x), Formatter(y)), but each operand is
 // formatted with "%# v".
 func Log(a ...interface{}) {
-	log.Print(wrap(a, true)...)
+	log.Print(wrap(a, true, useColor(log.Writer()))...)
 }
 
 // Logf is a convenience wrapper for log.Printf.
@@ -44,7 +45,7 @@ func Log(a ...interface{}) {
 // Calling Logf(f, x, y) is equivalent to
 // log.Printf(f, Formatter(x), Formatter(y)).
 func Logf(format string, a ...interface{}) {
-	log.Printf(format, wrap(a, false)...)
+	log.Printf(format, wrap(a, false, useColor(log.Writer()))...)
 }
 
 // Logln is a convenience wrapper for log.Printf.
@@ -53,7 +54,7 @@ func Logf(format string, a ...interface{}) {
 // log.Println(Formatter(x), Formatter(y)), but each operand is
 // formatted with "%# v".
 func Logln(a ...interface{}) {
-	log.Println(wrap(a, true)...)
+	log.Println(wrap(a, true, useColor(log.Writer()))...)
 }
 
 // Print pretty-prints its operands and writes to standard output.
@@ -62,7 +63,7 @@ func Logln(a ...interface{}) {
 // fmt.Print(Formatter(x), Formatter(y)), but each operand is
 // formatted with "%# v".
 func Print(a ...interface{}) (n int, errno error) {
-	return fmt.Print(wrap(a, true)...)
+	return fmt.Print(wrap(a, true, useColor(os.Stdout))...)
 }
 
 // Printf is a convenience wrapper for fmt.Printf.
@@ -70,7 +71,7 @@ func Print(a ...interface{}) (n int, errno error) {
 // Calling Printf(f, x, y) is equivalent to
 // fmt.Printf(f, Formatter(x), Formatter(y)).
 func Printf(format string, a ...interface{}) (n int, errno error) {
-	return fmt.Printf(format, wrap(a, false)...)
+	return fmt.Printf(format, wrap(a, false, useColor(os.Stdout))...)
 }
 
 // Println pretty-prints its operands and writes to standard output.
@@ -79,7 +80,7 @@ func Printf(format string, a ...interface{}) (n int, errno error) {
 // fmt.Println(Formatter(x), Formatter(y)), but each operand is
 // formatted with "%# v".
 func Println(a ...interface{}) (n int, errno error) {
-	return fmt.Println(wrap(a, true)...)
+	return fmt.Println(wrap(a, true, useColor(os.Stdout))...)
 }
 
 // Sprint is a convenience wrapper for fmt.Sprintf.
@@ -88,7 +89,7 @@ func Println(a ...interface{}) (n int, errno error) {
 // fmt.Sprint(Formatter(x), Formatter(y)), but each operand is
 // formatted with "%# v".
 func Sprint(a ...interface{}) string {
-	return fmt.Sprint(wrap(a, true)...)
+	return fmt.Sprint(wrap(a, true, false)...)
 }
 
 // Sprintf is a convenience wrapper for fmt.Sprintf.
@@ -96,13 +97,13 @@ func Sprint(a ...interface{}) string {
 // Calling Sprintf(f, x, y) is equivalent to
 // fmt.Sprintf(f, Formatter(x), Formatter(y)).
 func Sprintf(format string, a ...interface{}) string {
-	return fmt.Sprintf(format, wrap(a, false)...)
+	return fmt.Sprintf(format, wrap(a, false, false)...)
 }
 
-func wrap(a []interface{}, force bool) []interface{} {
+func wrap(a []interface{}, force, color bool) []interface{} {
 	w := make([]interface{}, len(a))
 	for i, x := range a {
-		w[i] = formatter{v: reflect.ValueOf(x), force: force}
+		w[i] = formatter{v: reflect.ValueOf(x), force: force, color: color}
 	}
 	return w
 }
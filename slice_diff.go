@@ -0,0 +1,591 @@
+package pretty
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// maxEditWork bounds the number of element comparisons and diagonals
+// editScript searches for hashed elements, maxEditWalks that for
+// elements compared by walking them. Once it is spent the elements left
+// are paired by position.
+const (
+	maxEditWork  = 1 << 20
+	maxEditWalks = 1 << 14
+)
+
+type editOp int
+
+const (
+	editMatch editOp = iota
+	editDelete
+	editInsert
+)
+
+// edit is a single step of an edit script. For editDelete only a is
+// meaningful, for editInsert only b.
+type edit struct {
+	op   editOp
+	a, b int
+}
+
+// editScript returns a shortest sequence of edits turning a sequence of
+// length n into a sequence of length m, where eq(i, j) reports whether
+// element i of the first sequence equals element j of the second.
+// It uses Myers' algorithm after trimming the common prefix and suffix.
+// If the search takes more than maxWork comparisons and diagonals, or
+// more than maxEdits edits if maxEdits >= 0, the script is shortest only
+// up to the furthest point reached.
+func editScript(n, m, maxWork, maxEdits int, eq func(i, j int) bool) []edit {
+	var pre, suf int
+	for pre < n && pre < m && eq(pre, pre) {
+		pre++
+	}
+	for suf < n-pre && suf < m-pre && eq(n-1-suf, m-1-suf) {
+		suf++
+	}
+
+	script := make([]edit, 0, n+m-pre-suf)
+	for i := 0; i < pre; i++ {
+		script = append(script, edit{op: editMatch, a: i, b: i})
+	}
+	script = append(script, myers(pre, n-suf, pre, m-suf, maxWork, maxEdits, eq)...)
+	for i := 0; i < suf; i++ {
+		script = append(script, edit{op: editMatch, a: n - suf + i, b: m - suf + i})
+	}
+	return script
+}
+
+// myers computes the edit script for a[a0:a1] and b[b0:b1].
+func myers(a0, a1, b0, b1, maxWork, maxEdits int, eq func(i, j int) bool) []edit {
+	n, m := a1-a0, b1-b0
+	if n == 0 && m == 0 {
+		return nil
+	}
+	max := n + m
+	v := make([]int, 2*max+2)
+	off := max + 1
+	var trace [][]int
+	var work int
+	for d := 0; d <= max; d++ {
+		// Keep the diagonals reachable at the previous step for backtracking.
+		trace = append(trace, append([]int(nil), v[off-d:off+d+1]...))
+		if maxEdits >= 0 && d > maxEdits {
+			return furthestScript(trace, n, m, a0, a1, b0, b1)
+		}
+		for k := -d; k <= d; k += 2 {
+			if work > maxWork {
+				return furthestScript(trace, n, m, a0, a1, b0, b1)
+			}
+			var x int
+			if k == -d || k != d && v[off+k-1] < v[off+k+1] {
+				x = v[off+k+1]
+			} else {
+				x = v[off+k-1] + 1
+			}
+			y := x - k
+			work++
+			for x < n && y < m && eq(a0+x, b0+y) {
+				x++
+				y++
+				work++
+			}
+			v[off+k] = x
+			if x >= n && y >= m {
+				return backtrack(trace, n, m, a0, b0)
+			}
+		}
+	}
+	return positionalScript(a0, a1, b0, b1)
+}
+
+// furthestScript is the script for a search given up in step d =
+// len(trace)-1: the edits leading to the point furthest from the start
+// reached in step d-1, followed by the positional script for the rest.
+func furthestScript(trace [][]int, n, m, a0, a1, b0, b1 int) []edit {
+	d := len(trace) - 1
+	if d == 0 {
+		return positionalScript(a0, a1, b0, b1)
+	}
+	v := trace[d]
+	bestX, bestY := -1, -1
+	for k := -(d - 1); k <= d-1; k += 2 {
+		x := v[k+d]
+		y := x - k
+		if x <= n && y >= 0 && y <= m && x+y > bestX+bestY {
+			bestX, bestY = x, y
+		}
+	}
+	return append(backtrack(trace[:d], bestX, bestY, a0, b0), positionalScript(a0+bestX, a1, b0+bestY, b1)...)
+}
+
+// backtrack returns the edits leading to the point (x, y) reached in
+// step len(trace)-1.
+func backtrack(trace [][]int, x, y, a0, b0 int) []edit {
+	var script []edit
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || k != d && v[k-1+d] < v[k+1+d] {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		var prevX, prevY int
+		if d > 0 {
+			prevX = v[prevK+d]
+			prevY = prevX - prevK
+		}
+		for x > prevX && y > prevY {
+			x--
+			y--
+			script = append(script, edit{op: editMatch, a: a0 + x, b: b0 + y})
+		}
+		if d > 0 {
+			if x == prevX {
+				script = append(script, edit{op: editInsert, b: b0 + y - 1})
+			} else {
+				script = append(script, edit{op: editDelete, a: a0 + x - 1})
+			}
+		}
+		x, y = prevX, prevY
+	}
+	for i, j := 0, len(script)-1; i < j; i, j = i+1, j-1 {
+		script[i], script[j] = script[j], script[i]
+	}
+	return script
+}
+
+// positionalScript deletes all of a[a0:a1] and inserts all of b[b0:b1],
+// which diffSlice then pairs up by position.
+func positionalScript(a0, a1, b0, b1 int) []edit {
+	script := make([]edit, 0, a1-a0+b1-b0)
+	for i := a0; i < a1; i++ {
+		script = append(script, edit{op: editDelete, a: i})
+	}
+	for j := b0; j < b1; j++ {
+		script = append(script, edit{op: editInsert, b: j})
+	}
+	return script
+}
+
+// diffSlice reports the differences between slices av and bv of
+// different lengths as an edit script. Runs of removed and inserted
+// elements are paired up and diffed as changed elements, the rest are
+// reported as missing on one side. Changed and removed elements are
+// labeled with their index in av, added elements with their index in bv.
+func (w diffPrinter) diffSlice(av, bv reflect.Value) {
+	if w.exhausted(av.Len() + bv.Len()) {
+		return
+	}
+	eq, hashed := w.elemEqual(av, bv)
+	maxWork := maxEditWalks
+	if hashed {
+		maxWork = maxEditWork
+	}
+	maxEdits := -1
+	if w.budget != nil {
+		// Every two edits are reported as at least one difference, so
+		// the walk stops before reaching any further edits.
+		maxEdits = 2 * (w.budget.max - w.budget.reported + 1)
+	}
+	script := editScript(av.Len(), bv.Len(), maxWork, maxEdits, eq)
+	var deleted, inserted []int
+	flush := func() {
+		n := len(deleted)
+		if len(inserted) < n {
+			n = len(inserted)
+		}
+		for k := 0; k < n; k++ {
+			w.descendIndex(deleted[k]).through().diff(av.Index(deleted[k]), bv.Index(inserted[k]))
+		}
+		for _, i := range deleted[n:] {
+			w.descendIndex(i).removed(av.Index(i))
+		}
+		for _, j := range inserted[n:] {
+			w.descendIndex(j).added(bv.Index(j))
+		}
+		deleted, inserted = deleted[:0], inserted[:0]
+	}
+	for _, e := range script {
+		switch e.op {
+		case editDelete:
+			deleted = append(deleted, e.a)
+		case editInsert:
+			inserted = append(inserted, e.b)
+		default:
+			flush()
+		}
+	}
+	flush()
+}
+
+// removed reports av as present in a but missing from b.
+func (w diffPrinter) removed(av reflect.Value) {
+	if w.ignored() || w.exhausted(1) {
+		return
+	}
+	w.printf("%# v != (missing)", formatter{v: av, quote: true})
+	w.structuredPrint(Removed, av, reflect.Value{}, fmt.Sprintf("%v", av), "(missing)")
+}
+
+// added reports bv as missing from a but present in b.
+func (w diffPrinter) added(bv reflect.Value) {
+	if w.ignored() || w.exhausted(1) {
+		return
+	}
+	w.printf("(missing) != %# v", formatter{v: bv, quote: true})
+	w.structuredPrint(Added, reflect.Value{}, bv, "(missing)", fmt.Sprintf("%v", bv))
+}
+
+// diffFound is the panic value a stopPrinter uses to abandon a walk
+// at the first difference.
+type diffFound struct{}
+
+type stopPrinter struct{}
+
+func (stopPrinter) Printf(format string, a ...interface{}) {
+	panic(diffFound{})
+}
+
+// equal reports whether av and bv have no differences under the options of w.
+// It walks with fresh visit maps and labels so w itself is left untouched.
+func (w diffPrinter) equal(av, bv reflect.Value) bool {
+	return w.stopping().compare(av, bv)
+}
+
+// stopping returns w set up for compare, with fresh visit maps and labels.
+func (w diffPrinter) stopping() diffPrinter {
+	w.w = stopPrinter{}
+	w.unlabeled = len(w.ignorePaths) == 0 && len(w.pathNumeric) == 0
+	w.structuredOutput = nil
+	w.budget = nil
+	w.labels = NewLabels()
+	w.aVisited = make(map[visit]visit)
+	w.bVisited = make(map[visit]visit)
+	return w
+}
+
+// compare reports whether av and bv have no differences, for w set up by
+// stopping. It empties the visit maps first, so that many calls can
+// share them.
+func (w diffPrinter) compare(av, bv reflect.Value) (eq bool) {
+	if len(w.aVisited) > 0 || len(w.bVisited) > 0 {
+		for k := range w.aVisited {
+			delete(w.aVisited, k)
+		}
+		for k := range w.bVisited {
+			delete(w.bVisited, k)
+		}
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(diffFound); !ok {
+				panic(r)
+			}
+			eq = false
+		}
+	}()
+	w.diff(av, bv)
+	return true
+}
+
+// elemEqual returns a function reporting whether element i of slice av
+// has no differences from element j of slice bv. If the elements are
+// equal exactly if they are ==, they are hashed once and hashed is true.
+// Otherwise each call walks the elements, sharing one set of visit maps.
+func (w diffPrinter) elemEqual(av, bv reflect.Value) (eq func(i, j int) bool, hashed bool) {
+	if ida, idb, ok := w.hashElems(av, bv); ok {
+		return func(i, j int) bool { return ida[i] == idb[j] }, true
+	}
+	return w.walkEqual(av, bv), false
+}
+
+// walkEqual is elemEqual for elements that are not hashed.
+func (w diffPrinter) walkEqual(av, bv reflect.Value) func(i, j int) bool {
+	s := w.stopping()
+	// The elements are not tracked as visited, as nothing in a fresh
+	// walk can have been visited before them.
+	return func(i, j int) bool {
+		return s.descendIndex(i).compare(av.Index(i), bv.Index(j))
+	}
+}
+
+// hashElems numbers the elements of slices av and bv so that elements
+// have the same number exactly if they are ==. It returns false if
+// elements that are not == could be equal under the options of w, or
+// if an element cannot be hashed.
+func (w diffPrinter) hashElems(av, bv reflect.Value) (ida, idb []int, ok bool) {
+	if av.Type() != bv.Type() || w.tagNumeric != nil || !w.plan(av.Type().Elem()).exact {
+		return nil, nil, false
+	}
+	ids := make(map[interface{}]int)
+	number := func(s reflect.Value) []int {
+		nums := make([]int, s.Len())
+		for i := range nums {
+			k, ok := keyIndex(s.Index(i))
+			if !ok {
+				return nil
+			}
+			id, seen := ids[k]
+			if !seen {
+				id = len(ids)
+				ids[k] = id
+			}
+			nums[i] = id
+		}
+		return nums
+	}
+	if ida = number(av); ida == nil {
+		return nil, nil, false
+	}
+	if idb = number(bv); idb == nil {
+		return nil, nil, false
+	}
+	return ida, idb, true
+}
+
+// sliceKeyFunc returns the identity of a slice element for keyed matching.
+// An invalid result only matches other invalid results.
+type sliceKeyFunc func(elem reflect.Value) reflect.Value
+
+func fieldKey(fieldName string) sliceKeyFunc {
+	return func(elem reflect.Value) reflect.Value {
+		for elem.Kind() == reflect.Ptr || elem.Kind() == reflect.Interface {
+			if elem.IsNil() {
+				return reflect.Value{}
+			}
+			elem = elem.Elem()
+		}
+		if elem.Kind() != reflect.Struct {
+			return reflect.Value{}
+		}
+		return elem.FieldByName(fieldName)
+	}
+}
+
+func funcKey(key func(elem interface{}) interface{}) sliceKeyFunc {
+	return func(elem reflect.Value) reflect.Value {
+		return reflect.ValueOf(key(interfaceOf(elem)))
+	}
+}
+
+// nilKey stands for an invalid key in keyIndex.
+type nilKey struct{}
+
+// typedKey distinguishes equal primitive key values of different types.
+type typedKey struct {
+	t reflect.Type
+	v interface{}
+}
+
+// keyIndex returns a map key for v, or false if v can only be
+// compared with keyEqual.
+func keyIndex(v reflect.Value) (interface{}, bool) {
+	if v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return nilKey{}, true
+	}
+	if v.CanInterface() && v.Type().Comparable() && hashable(v) {
+		return typedKey{v.Type(), v.Interface()}, true
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		return typedKey{v.Type(), v.Bool()}, true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return typedKey{v.Type(), v.Int()}, true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return typedKey{v.Type(), v.Uint()}, true
+	case reflect.Float32, reflect.Float64:
+		return typedKey{v.Type(), v.Float()}, true
+	case reflect.String:
+		return typedKey{v.Type(), v.String()}, true
+	}
+	return nil, false
+}
+
+// hashable reports whether v, of a comparable type, can be a map key,
+// which it cannot if it holds an interface with an incomparable value.
+func hashable(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Interface:
+		return v.IsNil() || v.Elem().Type().Comparable() && hashable(v.Elem())
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if !hashable(v.Index(i)) {
+				return false
+			}
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if !hashable(v.Field(i)) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// diffSliceByKey pairs the elements of av and bv with equal keys in order
+// of appearance and diffs each pair. Elements without a partner are
+// reported as missing on the other side.
+func (w diffPrinter) diffSliceByKey(av, bv reflect.Value, key sliceKeyFunc) {
+	indexed := make(map[interface{}][]int)
+	var unindexed []int
+	bKeys := make([]reflect.Value, bv.Len())
+	for j := range bKeys {
+		bKeys[j] = key(bv.Index(j))
+		if k, ok := keyIndex(bKeys[j]); ok {
+			indexed[k] = append(indexed[k], j)
+		} else {
+			unindexed = append(unindexed, j)
+		}
+	}
+
+	matched := make([]bool, bv.Len())
+	var pairs int
+	for i := 0; i < av.Len(); i++ {
+		// The elements of bv not paired yet are left uncompared as well.
+		if w.exhausted(av.Len() - i + bv.Len() - pairs) {
+			return
+		}
+		ak := key(av.Index(i))
+		j := -1
+		if k, ok := keyIndex(ak); ok {
+			if js := indexed[k]; len(js) > 0 {
+				j, indexed[k] = js[0], js[1:]
+			}
+		} else {
+			for n, bj := range unindexed {
+				if keyEqual(ak, bKeys[bj]) {
+					j = bj
+					unindexed = append(unindexed[:n], unindexed[n+1:]...)
+					break
+				}
+			}
+		}
+		w := w.descendIndex(i)
+		if j < 0 {
+			w.removed(av.Index(i))
+			continue
+		}
+		matched[j] = true
+		pairs++
+		w.through().diff(av.Index(i), bv.Index(j))
+	}
+	for j, ok := range matched {
+		if w.exhausted(bv.Len() - j) {
+			return
+		}
+		if !ok {
+			w.descendIndex(j).added(bv.Index(j))
+		}
+	}
+}
+
+// diffSliceUnordered compares av and bv as multisets, pairing as many
+// elements of av with equal elements of bv as possible. Elements that
+// are equal exactly if they are == are paired by hashing. Otherwise, as
+// equality with a tolerance is not transitive, pairing every element
+// with the first equal one could leave elements over that a different
+// pairing would match. Elements left over on either side are reported
+// as missing on the other.
+func (w diffPrinter) diffSliceUnordered(av, bv reflect.Value) {
+	var pair func(i int) bool
+	m := newMatching(bv.Len(), nil)
+	if ida, idb, ok := w.hashElems(av, bv); ok {
+		// Equality is transitive here, so any pairing of equal elements
+		// pairs as many as possible.
+		unpaired := make(map[int][]int)
+		for j := len(idb) - 1; j >= 0; j-- {
+			unpaired[idb[j]] = append(unpaired[idb[j]], j)
+		}
+		pair = func(i int) bool {
+			js := unpaired[ida[i]]
+			if len(js) == 0 {
+				return false
+			}
+			m.pairOfB[js[len(js)-1]] = i
+			unpaired[ida[i]] = js[:len(js)-1]
+			return true
+		}
+	} else {
+		m.eq = w.walkEqual(av, bv)
+		pair = m.augment
+	}
+	var pairs int
+	for i := 0; i < av.Len(); i++ {
+		if w.exhausted(av.Len() - i + bv.Len() - pairs) {
+			return
+		}
+		// An element that cannot be paired now cannot be paired later
+		// either, so it is reported right away.
+		if pair(i) {
+			pairs++
+		} else {
+			w.descendIndex(i).removed(av.Index(i))
+		}
+	}
+	for j, i := range m.pairOfB {
+		if w.exhausted(bv.Len() - j) {
+			return
+		}
+		if i < 0 {
+			w.descendIndex(j).added(bv.Index(j))
+		}
+	}
+}
+
+// matching is a maximum bipartite matching between the elements of two
+// sequences, the second of length m, built with augmenting paths (Kuhn's
+// algorithm).
+type matching struct {
+	eq      func(i, j int) bool
+	pairOfB []int // index of the element of the first sequence paired with j, or -1
+	seen    []int // round in which j was visited by augment
+	round   int
+}
+
+func newMatching(m int, eq func(i, j int) bool) *matching {
+	mt := &matching{
+		eq:      eq,
+		pairOfB: make([]int, m),
+		seen:    make([]int, m),
+	}
+	for j := range mt.pairOfB {
+		mt.pairOfB[j] = -1
+	}
+	return mt
+}
+
+// augment pairs element i of the first sequence, re-pairing others if
+// necessary, and reports whether it could.
+func (mt *matching) augment(i int) bool {
+	mt.round++
+	return mt.try(i)
+}
+
+func (mt *matching) try(i int) bool {
+	// Prefer an unpaired equal element, which keeps the common case of
+	// exact equality as cheap as pairing greedily.
+	for j, p := range mt.pairOfB {
+		if p < 0 && mt.seen[j] != mt.round && mt.eq(i, j) {
+			mt.pairOfB[j] = i
+			return true
+		}
+	}
+	for j, p := range mt.pairOfB {
+		if p < 0 || mt.seen[j] == mt.round || !mt.eq(i, j) {
+			continue
+		}
+		mt.seen[j] = mt.round
+		if mt.try(p) {
+			mt.pairOfB[j] = i
+			return true
+		}
+	}
+	return false
+}
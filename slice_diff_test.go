@@ -0,0 +1,179 @@
+package pretty
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func lcsLen(a, b []int) int {
+	t := make([][]int, len(a)+1)
+	for i := range t {
+		t[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			switch {
+			case a[i] == b[j]:
+				t[i][j] = t[i+1][j+1] + 1
+			case t[i+1][j] > t[i][j+1]:
+				t[i][j] = t[i+1][j]
+			default:
+				t[i][j] = t[i][j+1]
+			}
+		}
+	}
+	return t[0][0]
+}
+
+// checkScript fails t if script does not turn a into b, and returns the
+// number of matched elements.
+func checkScript(t *testing.T, a, b []int, script []edit) (matches int) {
+	t.Helper()
+	var i, j int
+	for _, e := range script {
+		switch e.op {
+		case editMatch:
+			if e.a != i || e.b != j || a[i] != b[j] {
+				t.Fatalf("editScript(%v, %v): bad match %+v", a, b, e)
+			}
+			i++
+			j++
+			matches++
+		case editDelete:
+			if e.a != i {
+				t.Fatalf("editScript(%v, %v): bad delete %+v", a, b, e)
+			}
+			i++
+		case editInsert:
+			if e.b != j {
+				t.Fatalf("editScript(%v, %v): bad insert %+v", a, b, e)
+			}
+			j++
+		}
+	}
+	if i != len(a) || j != len(b) {
+		t.Fatalf("editScript(%v, %v) does not cover both sequences", a, b)
+	}
+	return matches
+}
+
+func TestEditScript(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for n := 0; n < 200; n++ {
+		a := make([]int, r.Intn(20))
+		b := make([]int, r.Intn(20))
+		for i := range a {
+			a[i] = r.Intn(5)
+		}
+		for i := range b {
+			b[i] = r.Intn(5)
+		}
+		script := editScript(len(a), len(b), maxEditWork, -1, func(i, j int) bool { return a[i] == b[j] })
+		if want, matches := lcsLen(a, b), checkScript(t, a, b, script); matches != want {
+			t.Errorf("editScript(%v, %v) matched %d elements, want %d", a, b, matches, want)
+		}
+
+		// A search cut short still yields a valid script.
+		var calls int
+		script = editScript(len(a), len(b), 10, -1, func(i, j int) bool {
+			calls++
+			return a[i] == b[j]
+		})
+		checkScript(t, a, b, script)
+		// Trimming the common prefix and suffix is not counted.
+		if max := 10 + len(a) + len(b) + 2; calls > max {
+			t.Errorf("editScript(%v, %v) compared %d times, want at most %d", a, b, calls, max)
+		}
+
+		maxEdits := r.Intn(4)
+		script = editScript(len(a), len(b), maxEditWork, maxEdits, func(i, j int) bool { return a[i] == b[j] })
+		checkScript(t, a, b, script)
+		// Up to the furthest point reached the script is shortest.
+		var edits int
+		for _, e := range script {
+			if e.op != editMatch {
+				edits++
+			}
+		}
+		if want := len(a) + len(b) - 2*lcsLen(a, b); edits > want && edits <= maxEdits {
+			t.Errorf("editScript(%v, %v) with %d edits at most has %d edits, want %d", a, b, maxEdits, edits, want)
+		}
+	}
+}
+
+func TestEditScriptFurthest(t *testing.T) {
+	a := []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 100}
+	b := []int{1, 99, 2, 3, 4, 5, 6, 7, 8, 9, 10, 13, 14, 12, 0}
+	// The insertion of 99 is found before the search is given up, the
+	// rest is paired by position although 13 and 14 could be matched.
+	script := editScript(len(a), len(b), 12, -1, func(i, j int) bool { return a[i] == b[j] })
+	checkScript(t, a, b, script)
+	want := []edit{{op: editMatch}, {op: editInsert, b: 1}}
+	for i := 1; i < 10; i++ {
+		want = append(want, edit{op: editMatch, a: i, b: i + 1})
+	}
+	for i := 10; i < 15; i++ {
+		want = append(want, edit{op: editDelete, a: i})
+	}
+	for j := 11; j < 15; j++ {
+		want = append(want, edit{op: editInsert, b: j})
+	}
+	assert.Equal(t, want, script)
+}
+
+func TestElemEqualHashed(t *testing.T) {
+	type plain struct {
+		ID    int
+		Name  string
+		Price float64
+	}
+	type ptr struct {
+		P *int
+	}
+	tests := []struct {
+		name    string
+		c       Comparator
+		a, b    interface{}
+		want    bool
+		eqFirst bool
+	}{
+		{"plain structs", NewCustomDiff(), []plain{{ID: 1}}, []plain{{ID: 1}}, true, true},
+		{"epsilon", NewCustomDiff(WithNumericEpsilon(0.1)), []plain{{Price: 1}}, []plain{{Price: 1.05}}, false, true},
+		{"epsilon exact integers", NewCustomDiff(WithNumericEpsilon(0.1), WithExactIntegers(true)), []int{1}, []int{2}, true, false},
+		{"strings with epsilon", NewCustomDiff(WithNumericEpsilon(0.1)), []string{"a"}, []string{"a"}, true, true},
+		{"pointers", NewCustomDiff(), []ptr{{new(int)}}, []ptr{{new(int)}}, false, true},
+		{"ignore paths", NewCustomDiff(WithIgnorePaths("[*].Name")), []plain{{Name: "a"}}, []plain{{Name: "b"}}, false, true},
+		{"custom comparator", NewCustomDiff(WithCustomComparators(map[reflect.Type]Equals{
+			reflect.TypeOf(""): func(a, b interface{}) bool { return true },
+		})), []string{"a"}, []string{"b"}, false, true},
+	}
+	for _, tt := range tests {
+		w := tt.c.(*customDiffPrinter).printer(nil)
+		eq, hashed := w.elemEqual(reflect.ValueOf(tt.a), reflect.ValueOf(tt.b))
+		assert.Equal(t, tt.want, hashed, tt.name)
+		assert.Equal(t, tt.eqFirst, eq(0, 0), tt.name)
+	}
+}
+
+func TestStructuredDiffSliceInsert(t *testing.T) {
+	type record struct {
+		ID   int
+		Name string
+	}
+	a := make([]record, 500)
+	for i := range a {
+		a[i] = record{ID: i, Name: "r"}
+	}
+	b := append(append(append([]record(nil), a[:250]...), record{ID: 1000, Name: "new"}), a[250:]...)
+	b[400].Name = "changed"
+
+	got, ok := NewCustomDiff().StructuredDiff(a, b)
+	assert.False(t, ok)
+	assert.Equal(t, []StructuredDiff{
+		{FieldName: "[250]", Labels: []Label{}, ValueA: "(missing)", ValueB: "{1000 new}", Kind: Added, B: record{ID: 1000, Name: "new"}, Path: Path{indexStep(250)}},
+		{FieldName: "[399].Name", Labels: []Label{}, ValueA: "\"r\"", ValueB: "\"changed\"", A: "r", B: "changed", Path: Path{indexStep(399), fieldStep("Name")}},
+	}, got)
+}
@@ -0,0 +1,64 @@
+package pretty
+
+import (
+	"errors"
+	"flag"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// snapshotUpdate reports whether Snapshot writes golden files: if the
+// PRETTY_UPDATE environment variable is not empty or a bool -update flag
+// defined by the test binary is set. The package defines no flag itself,
+// as it would be added to every program importing it.
+func snapshotUpdate() bool {
+	if os.Getenv("PRETTY_UPDATE") != "" {
+		return true
+	}
+	if f := flag.Lookup("update"); f != nil {
+		if g, ok := f.Value.(flag.Getter); ok {
+			update, _ := g.Get().(bool)
+			return update
+		}
+	}
+	return false
+}
+
+// Snapshot compares value, pretty-printed with "%# v", with the golden
+// file testdata/<test name>/<name>.golden and fails t with a unified diff
+// if they differ, or if the golden file does not exist. In update mode,
+// requested with PRETTY_UPDATE=1 or the -update flag of the test binary,
+// the golden file is written instead.
+//
+// The golden file holds only the printed text, which cannot be decoded
+// back into a value, so Snapshot compares the texts rather than using a
+// Comparator: tolerances and ignored fields do not apply.
+func Snapshot(t testing.TB, name string, value interface{}) {
+	t.Helper()
+	got := Sprint(value) + "\n"
+	path := filepath.Join("testdata", filepath.FromSlash(t.Name()), name+".golden")
+	want, err := os.ReadFile(path)
+	if snapshotUpdate() {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("pretty: cannot write snapshot: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatalf("pretty: cannot write snapshot: %v", err)
+		}
+		t.Logf("pretty: wrote snapshot %s", path)
+		return
+	}
+	if errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("pretty: snapshot %s does not exist, run the test with PRETTY_UPDATE=1 to write it", path)
+		return
+	}
+	if err != nil {
+		t.Fatalf("pretty: cannot read snapshot: %v", err)
+	}
+	if string(want) != got {
+		t.Errorf("pretty: value differs from snapshot %s, run the test with PRETTY_UPDATE=1 to accept it:\n%s",
+			path, unifiedText(path, "got", string(want), got))
+	}
+}
@@ -0,0 +1,62 @@
+package pretty
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	assert.NoError(t, err)
+	assert.NoError(t, os.Chdir(dir))
+	defer os.Chdir(wd)
+
+	type config struct {
+		Name  string
+		Ports []int
+	}
+	r := &recordingTB{TB: t}
+	Snapshot(r, "config", config{Name: "a", Ports: []int{80}})
+	assert.Equal(t, []string{"pretty: snapshot testdata/TestSnapshot/config.golden does not exist, run the test with PRETTY_UPDATE=1 to write it"}, r.errors)
+
+	t.Setenv("PRETTY_UPDATE", "1")
+	r = &recordingTB{TB: t}
+	Snapshot(r, "config", config{Name: "a", Ports: []int{80}})
+	assert.Empty(t, r.errors)
+	golden, err := os.ReadFile(filepath.Join("testdata", "TestSnapshot", "config.golden"))
+	assert.NoError(t, err)
+	assert.Equal(t, "pretty.config{\n    Name:  \"a\",\n    Ports: {80},\n}\n", string(golden))
+
+	t.Setenv("PRETTY_UPDATE", "")
+	Snapshot(r, "config", config{Name: "a", Ports: []int{80}})
+	assert.Empty(t, r.errors)
+
+	Snapshot(r, "config", config{Name: "b", Ports: []int{80}})
+	assert.Equal(t, []string{`pretty: value differs from snapshot testdata/TestSnapshot/config.golden, run the test with PRETTY_UPDATE=1 to accept it:
+--- testdata/TestSnapshot/config.golden
++++ got
+@@ -1,4 +1,4 @@
+ pretty.config{
+-    Name:  "a",
++    Name:  "b",
+     Ports: {80},
+ }
+`}, r.errors)
+
+	t.Setenv("PRETTY_UPDATE", "1")
+	r = &recordingTB{TB: t}
+	Snapshot(r, "config", config{Name: "b", Ports: []int{80}})
+	assert.Empty(t, r.errors)
+	golden, err = os.ReadFile(filepath.Join("testdata", "TestSnapshot", "config.golden"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(golden), `"b"`)
+}
+
+func TestSnapshotDefinesNoFlag(t *testing.T) {
+	assert.Nil(t, flag.Lookup("pretty.update"))
+}
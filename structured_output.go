@@ -1,10 +1,85 @@
 package pretty
 
+import "strconv"
+
 type StructuredDiff struct {
 	FieldName string
 	Labels    []Label
 	ValueA    string
 	ValueB    string
+	// Kind tells what the difference is.
+	Kind DiffKind
+	// A and B are the differing values. A is nil if Kind is Added and
+	// B is nil if Kind is Removed. Both are nil for values reached
+	// through unexported struct fields, see reflect.Value.CanInterface.
+	A, B interface{}
+	// Path is the path to the differing values. Unlike FieldName it
+	// holds map keys as they are and the pointers and interfaces
+	// passed through.
+	Path Path
+	// Numeric is set if A and B are numbers, to their deltas.
+	Numeric *NumericDelta
+}
+
+// NumericDelta describes how far apart two differing numbers are.
+type NumericDelta struct {
+	// A and B are the numbers converted to float64.
+	A, B float64
+	// Absolute is |A-B|.
+	Absolute float64
+	// Relative is |A-B| / max(|A|,|B|), as in Tolerance.Relative.
+	Relative float64
+}
+
+func newNumericDelta(a, b float64) *NumericDelta {
+	return &NumericDelta{A: a, B: b, Absolute: absoluteDelta(a, b), Relative: relativeDelta(a, b)}
+}
+
+// DiffKind classifies a StructuredDiff.
+type DiffKind int
+
+const (
+	// Changed means A and B are different values of the same type.
+	Changed DiffKind = iota
+	// Added means B is a slice element or map value missing from a.
+	Added
+	// Removed means A is a slice element or map value missing from b.
+	Removed
+	// TypeMismatch means A and B are of different types.
+	TypeMismatch
+	// NilMismatch means exactly one of A and B is nil.
+	NilMismatch
+	// LengthMismatch means A and B are arrays of different lengths,
+	// compared under WithIgnoreTypeNameDiffs. Slices of different
+	// lengths are reported element by element with Added and Removed.
+	LengthMismatch
+	// Cycle means one of A and B was visited before on the way to it
+	// and the other was not.
+	Cycle
+	// Truncated marks the end of a walk stopped by WithMaxDiffs.
+	Truncated
+)
+
+func (k DiffKind) String() string {
+	switch k {
+	case Changed:
+		return "changed"
+	case Added:
+		return "added"
+	case Removed:
+		return "removed"
+	case TypeMismatch:
+		return "type mismatch"
+	case NilMismatch:
+		return "nil mismatch"
+	case LengthMismatch:
+		return "length mismatch"
+	case Cycle:
+		return "cycle"
+	case Truncated:
+		return "truncated"
+	}
+	return "DiffKind(" + strconv.Itoa(int(k)) + ")"
 }
 
 type Label struct {
@@ -32,3 +107,16 @@ func (d *differ) Results() []StructuredDiff {
 func NewStructuredDiffer() StructuredDiffer {
 	return &differ{}
 }
+
+// StructuredDifferFunc is a StructuredDiffer calling f for every
+// difference, e.g. to write it to a file or send it on a channel. It
+// keeps nothing, so Results always returns nil.
+type StructuredDifferFunc func(diff StructuredDiff)
+
+func (f StructuredDifferFunc) Print(diff StructuredDiff) {
+	f(diff)
+}
+
+func (f StructuredDifferFunc) Results() []StructuredDiff {
+	return nil
+}
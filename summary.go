@@ -0,0 +1,155 @@
+package pretty
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Summary aggregates structured diffs into counts, e.g. to report that
+// Orders[*].Price differed in 1204 orders by at most 0.03. Add diffs to
+// it one by one, as StructuredDifferFunc(s.Add) for
+// Comparator.StructuredDiffTo, or all at once with Summarize.
+type Summary struct {
+	// Total is the number of differences, not counting the Truncated marker.
+	Total int
+	// Kinds counts the differences by kind.
+	Kinds map[DiffKind]int
+	// Paths holds the statistics of each path pattern, which is the
+	// FieldName of a difference with all indices and map keys
+	// replaced by "[*]".
+	Paths map[string]*PathSummary
+}
+
+// PathSummary holds the statistics of the differences at a path pattern.
+type PathSummary struct {
+	// Count is the number of differences.
+	Count int
+	// Numeric is the number of differences with StructuredDiff.Numeric
+	// set among them, which the deltas are computed of.
+	Numeric int
+	// MaxAbsolute and MeanAbsolute are the maximum and mean of |a-b|.
+	MaxAbsolute, MeanAbsolute float64
+	// MaxRelative and MeanRelative are the maximum and mean of
+	// |a-b| / max(|a|,|b|).
+	MaxRelative, MeanRelative float64
+}
+
+// Summarize returns the Summary of diffs.
+func Summarize(diffs []StructuredDiff) *Summary {
+	s := &Summary{}
+	for _, d := range diffs {
+		s.Add(d)
+	}
+	return s
+}
+
+// Add counts d.
+func (s *Summary) Add(d StructuredDiff) {
+	if s.Kinds == nil {
+		s.Kinds = make(map[DiffKind]int)
+		s.Paths = make(map[string]*PathSummary)
+	}
+	s.Kinds[d.Kind]++
+	if d.Kind == Truncated {
+		return
+	}
+	s.Total++
+	pattern := pathPatternOf(d)
+	p, ok := s.Paths[pattern]
+	if !ok {
+		p = &PathSummary{}
+		s.Paths[pattern] = p
+	}
+	p.Count++
+	if d.Numeric == nil {
+		return
+	}
+	abs, rel := d.Numeric.Absolute, d.Numeric.Relative
+	p.Numeric++
+	p.MaxAbsolute = math.Max(p.MaxAbsolute, abs)
+	p.MaxRelative = math.Max(p.MaxRelative, rel)
+	// Running means, so that a single huge delta does not overflow a sum.
+	p.MeanAbsolute += (abs - p.MeanAbsolute) / float64(p.Numeric)
+	p.MeanRelative += (rel - p.MeanRelative) / float64(p.Numeric)
+}
+
+// String lists the kinds and then the path patterns, the most frequent first.
+func (s *Summary) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d differences", s.Total)
+	kinds := make([]DiffKind, 0, len(s.Kinds))
+	for k := range s.Kinds {
+		kinds = append(kinds, k)
+	}
+	sort.Slice(kinds, func(i, j int) bool { return kinds[i] < kinds[j] })
+	for i, k := range kinds {
+		if i == 0 {
+			b.WriteString(": ")
+		} else {
+			b.WriteString(", ")
+		}
+		fmt.Fprintf(&b, "%d %v", s.Kinds[k], k)
+	}
+	for _, pattern := range s.patterns() {
+		p := s.Paths[pattern]
+		name := pattern
+		if name == "" {
+			name = "(root)"
+		}
+		fmt.Fprintf(&b, "\n%s: %d", name, p.Count)
+		if p.Numeric > 0 {
+			fmt.Fprintf(&b, ", max delta %v (%.3g%%), mean delta %v (%.3g%%)",
+				p.MaxAbsolute, 100*p.MaxRelative, p.MeanAbsolute, 100*p.MeanRelative)
+		}
+	}
+	return b.String()
+}
+
+// patterns returns the path patterns of s by descending count.
+func (s *Summary) patterns() []string {
+	patterns := make([]string, 0, len(s.Paths))
+	for pattern := range s.Paths {
+		patterns = append(patterns, pattern)
+	}
+	sort.Slice(patterns, func(i, j int) bool {
+		ci, cj := s.Paths[patterns[i]].Count, s.Paths[patterns[j]].Count
+		if ci != cj {
+			return ci > cj
+		}
+		return patterns[i] < patterns[j]
+	})
+	return patterns
+}
+
+// pathPatternOf returns the FieldName of d with indices and map keys
+// replaced by "[*]".
+func pathPatternOf(d StructuredDiff) string {
+	var elems []string
+	if d.Path != nil {
+		for _, s := range d.Path {
+			switch s.Kind {
+			case FieldStep:
+				elems = append(elems, s.Name)
+			case IndexStep, KeyStep:
+				elems = append(elems, "[*]")
+			}
+		}
+	} else {
+		elems = splitPath(d.FieldName)
+		for i, e := range elems {
+			if e[0] == '[' {
+				elems[i] = "[*]"
+			}
+		}
+	}
+	var b strings.Builder
+	for i, e := range elems {
+		if i > 0 && e[0] != '[' {
+			b.WriteString(sep)
+		}
+		b.WriteString(e)
+	}
+	return b.String()
+}
@@ -0,0 +1,52 @@
+package pretty
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSummary(t *testing.T) {
+	type order struct {
+		ID    string
+		Price float64
+		Qty   int
+		Tags  map[string]string
+	}
+	a := []order{
+		{ID: "1", Price: 10, Qty: 1},
+		{ID: "2", Price: 20, Qty: 2, Tags: map[string]string{"x": "a", "y": "a"}},
+		{ID: "3", Price: 40, Qty: 3},
+	}
+	b := []order{
+		{ID: "1", Price: 10.5, Qty: 1},
+		{ID: "2", Price: 20, Qty: 2, Tags: map[string]string{"x": "b", "y": "b"}},
+		{ID: "3", Price: 38, Qty: 0},
+	}
+
+	s := &Summary{}
+	ok := NewCustomDiff().StructuredDiffTo(StructuredDifferFunc(s.Add), a, b)
+	assert.False(t, ok)
+	assert.Equal(t, 5, s.Total)
+	assert.Equal(t, map[DiffKind]int{Changed: 5}, s.Kinds)
+	assert.Equal(t, map[string]*PathSummary{
+		"[*].Price":   {Count: 2, Numeric: 2, MaxAbsolute: 2, MeanAbsolute: 1.25, MaxRelative: 0.05, MeanRelative: (0.5/10.5 + 0.05) / 2},
+		"[*].Qty":     {Count: 1, Numeric: 1, MaxAbsolute: 3, MeanAbsolute: 3, MaxRelative: 1, MeanRelative: 1},
+		"[*].Tags[*]": {Count: 2},
+	}, s.Paths)
+
+	diffs, _ := NewCustomDiff(WithMaxDiffs(1)).StructuredDiff(a, b)
+	assert.Equal(t, "1 differences: 1 changed, 1 truncated\n"+
+		"[*].Price: 1, max delta 0.5 (4.76%), mean delta 0.5 (4.76%)", Summarize(diffs).String())
+}
+
+func TestSummaryFieldNames(t *testing.T) {
+	s := Summarize([]StructuredDiff{
+		{FieldName: `M["a.b"].X`, Kind: Added},
+		{FieldName: `M[[2]int{1, 2}].X`, Kind: Removed},
+		{FieldName: "", Kind: TypeMismatch, A: 1, B: "1"},
+	})
+	assert.Equal(t, "3 differences: 1 added, 1 removed, 1 type mismatch\n"+
+		"M[*].X: 2\n"+
+		"(root): 1", s.String())
+}
@@ -0,0 +1,142 @@
+package pretty
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// fieldTag is the diff configuration of a struct field given by its
+// `pretty:"..."` tag, a comma separated list of
+//
+//	"-"            ignore the field
+//	"label"        use the field as a label, like WithLabelFields
+//	"unordered"    compare the slice as a multiset, like WithUnorderedSlices
+//	"epsilon=0.01" compare numbers with this absolute tolerance
+//	"rel=0.001"    compare numbers with this relative tolerance
+//	"ulps=4"       compare numbers allowing this many float64 steps apart
+//
+// Several numeric tolerances are combined: numbers are equal if any of
+// them is satisfied.
+// Unknown or malformed options are ignored, as in encoding/json.
+type fieldTag struct {
+	ignore    bool
+	label     bool
+	unordered bool
+	tolerance *Tolerance
+}
+
+func parseFieldTag(tag string) (ft fieldTag) {
+	var tolerance Tolerance
+	var numeric bool
+	for _, opt := range strings.Split(tag, ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(opt), "=")
+		switch name {
+		case "-":
+			ft.ignore = true
+		case "label":
+			ft.label = true
+		case "unordered":
+			ft.unordered = true
+		case "epsilon":
+			if e, err := strconv.ParseFloat(value, 64); err == nil {
+				tolerance.Absolute, numeric = e, true
+			}
+		case "rel":
+			if r, err := strconv.ParseFloat(value, 64); err == nil {
+				tolerance.Relative, numeric = r, true
+			}
+		case "ulps":
+			if u, err := strconv.ParseUint(value, 10, 64); err == nil {
+				tolerance.ULPs, numeric = u, true
+			}
+		}
+	}
+	if numeric {
+		ft.tolerance = &tolerance
+	}
+	return ft
+}
+
+// structTagsCache maps a struct reflect.Type to its []fieldTag.
+var structTagsCache sync.Map
+
+// structTags returns the parsed tags of the fields of struct type t,
+// or nil if none of them has a pretty tag.
+func structTags(t reflect.Type) []fieldTag {
+	if tags, ok := structTagsCache.Load(t); ok {
+		return tags.([]fieldTag)
+	}
+	var tags []fieldTag
+	for i := 0; i < t.NumField(); i++ {
+		tag, ok := t.Field(i).Tag.Lookup("pretty")
+		if !ok {
+			continue
+		}
+		if tags == nil {
+			tags = make([]fieldTag, t.NumField())
+		}
+		tags[i] = parseFieldTag(tag)
+	}
+	structTagsCache.Store(t, tags)
+	return tags
+}
+
+// taggedLabelsCache maps a reflect.Type to its tagged label names.
+var taggedLabelsCache sync.Map
+
+// taggedLabels returns the names of the string fields tagged
+// `pretty:"label"` in t and the types reachable from it, in the order a
+// walk meets them. Types behind interfaces are only known to the walk.
+func taggedLabels(t reflect.Type) []string {
+	if t == nil {
+		return nil
+	}
+	if names, ok := taggedLabelsCache.Load(t); ok {
+		return names.([]string)
+	}
+	var names []string
+	seen := make(map[reflect.Type]bool)
+	var walk func(t reflect.Type)
+	walk = func(t reflect.Type) {
+		if seen[t] {
+			return
+		}
+		seen[t] = true
+		switch t.Kind() {
+		case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+			walk(t.Elem())
+		case reflect.Struct:
+			tags := structTags(t)
+			if tags == nil {
+				for i := 0; i < t.NumField(); i++ {
+					walk(t.Field(i).Type)
+				}
+				return
+			}
+			for i := 0; i < t.NumField(); i++ {
+				if f := t.Field(i); tags[i].label && f.Type.Kind() == reflect.String && !contains(names, f.Name) {
+					names = append(names, f.Name)
+				}
+			}
+			for i := 0; i < t.NumField(); i++ {
+				if !tags[i].ignore {
+					walk(t.Field(i).Type)
+				}
+			}
+		}
+	}
+	walk(t)
+	taggedLabelsCache.Store(t, names)
+	return names
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,119 @@
+package pretty
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseFieldTag(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want fieldTag
+	}{
+		{"-", fieldTag{ignore: true}},
+		{"label", fieldTag{label: true}},
+		{"unordered, label", fieldTag{unordered: true, label: true}},
+		{"epsilon=oops,unknown", fieldTag{}},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, parseFieldTag(tt.tag), tt.tag)
+	}
+	assert.Equal(t, &Tolerance{Absolute: 0.01}, parseFieldTag("epsilon=0.01").tolerance)
+	assert.Equal(t, &Tolerance{Absolute: 1e-6, Relative: 1e-3, ULPs: 4}, parseFieldTag("epsilon=1e-6,rel=1e-3,ulps=4").tolerance)
+}
+
+func TestStructuredDiffTags(t *testing.T) {
+	type line struct {
+		SKU    string  `pretty:"label"`
+		Price  float64 `pretty:"epsilon=0.01"`
+		Weight float64
+	}
+	type order struct {
+		ID        string `pretty:"label"`
+		UpdatedAt int64  `pretty:"-"`
+		Lines     []line `pretty:"unordered"`
+		Tags      []string
+	}
+	a := order{
+		ID:        "o1",
+		UpdatedAt: 1,
+		Lines:     []line{{"a", 1, 1}, {"b", 2, 2}},
+		Tags:      []string{"x", "y"},
+	}
+	b := order{
+		ID:        "o1",
+		UpdatedAt: 2,
+		Lines:     []line{{"b", 2.005, 2}, {"a", 1.005, 1}},
+		Tags:      []string{"y", "x"},
+	}
+
+	got, ok := NewCustomDiff().StructuredDiff(a, b)
+	assert.False(t, ok)
+	assert.Equal(t, []StructuredDiff{
+		{FieldName: "Tags[0]", Labels: []Label{{Name: "ID", Value: "o1"}, {Name: "SKU"}}, ValueA: `"x"`, ValueB: `"y"`, A: "x", B: "y", Path: Path{fieldStep("Tags"), indexStep(0)}},
+		{FieldName: "Tags[1]", Labels: []Label{{Name: "ID", Value: "o1"}, {Name: "SKU"}}, ValueA: `"y"`, ValueB: `"x"`, A: "y", B: "x", Path: Path{fieldStep("Tags"), indexStep(1)}},
+	}, got)
+
+	b.Lines[0].Weight = 3
+	got, _ = NewCustomDiff().StructuredDiff(a, b)
+	assert.Equal(t, StructuredDiff{
+		FieldName: "Lines[1]",
+		Labels:    []Label{{Name: "ID", Value: "o1"}, {Name: "SKU"}},
+		ValueA:    "{b 2 2}",
+		ValueB:    "(missing)",
+		Kind:      Removed,
+		A:         got[0].A,
+		Path:      Path{fieldStep("Lines"), indexStep(1)},
+	}, got[0])
+}
+
+func TestTaggedLabelsBeforeTaggedFields(t *testing.T) {
+	type item struct {
+		SKU string `pretty:"label"`
+		Qty int
+	}
+	type doc struct {
+		Name  string
+		Items []item
+	}
+	got, _ := NewCustomDiff().StructuredDiff(
+		doc{Name: "a", Items: []item{{"x", 1}}},
+		doc{Name: "b", Items: []item{{"x", 2}}},
+	)
+	assert.Len(t, got, 2)
+	assert.Equal(t, []Label{{Name: "SKU"}}, got[0].Labels)
+	assert.Equal(t, []Label{{Name: "SKU", Value: "x"}}, got[1].Labels)
+}
+
+func TestTaggedLabelsConcurrent(t *testing.T) {
+	type order struct {
+		ID    string `pretty:"label"`
+		Price float64
+	}
+	// Spare capacity, so that appending the tagged label to the names
+	// would write to an array shared by all walks.
+	names := make([]string, 17, 32)
+	for i := range names {
+		names[i] = fmt.Sprintf("Name%d", i)
+	}
+	c := NewCustomDiff(WithLabelFields(names...))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				got, _ := c.StructuredDiff(order{"o1", 1}, order{"o1", 2})
+				if len(got) != 1 || got[0].Labels[len(names)] != (Label{Name: "ID", Value: "o1"}) {
+					t.Errorf("got %v", got)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
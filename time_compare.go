@@ -0,0 +1,65 @@
+package pretty
+
+import (
+	"reflect"
+	"time"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// timeComparison configures how time.Time values are compared.
+// The zero value compares their String representation, which
+// includes the location.
+type timeComparison struct {
+	instant        bool
+	ignoreLocation bool
+	truncate       time.Duration
+	tolerance      time.Duration
+}
+
+// comparator returns the equality function for c, or nil for
+// comparison by String.
+func (c timeComparison) comparator() func(a, b time.Time) bool {
+	if c == (timeComparison{}) {
+		return nil
+	}
+	return func(a, b time.Time) bool {
+		if c.ignoreLocation {
+			a, b = wallClock(a), wallClock(b)
+		}
+		if c.truncate > 0 {
+			a, b = a.Truncate(c.truncate), b.Truncate(c.truncate)
+		}
+		if c.tolerance <= 0 {
+			return a.Equal(b)
+		}
+		d := a.Sub(b)
+		if d < 0 {
+			d = -d
+		}
+		return d <= c.tolerance
+	}
+}
+
+// wallClock returns the wall clock reading of t as if it was taken in UTC.
+func wallClock(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+}
+
+// diffTime compares av and bv, which are convertible to time.Time.
+// Monotonic clock readings are always ignored.
+func (w diffPrinter) diffTime(av, bv reflect.Value) {
+	atime := reflect.ValueOf(interfaceOf(av)).Convert(timeType).Interface().(time.Time).Round(0)
+	btime := reflect.ValueOf(interfaceOf(bv)).Convert(timeType).Interface().(time.Time).Round(0)
+	var equal bool
+	if w.timeEquals != nil {
+		equal = w.timeEquals(atime, btime)
+	} else {
+		// The same instant in the same location always prints the same.
+		equal = atime.Equal(btime) && atime.Location() == btime.Location() || atime.String() == btime.String()
+	}
+	if !equal {
+		w.printf("%v != %v", atime.String(), btime.String())
+		w.structuredPrint(Changed, av, bv, atime.String(), btime.String())
+	}
+}
@@ -0,0 +1,105 @@
+package pretty
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_customDiffPrinter_TimeComparison(t *testing.T) {
+	type event struct {
+		At time.Time
+	}
+	cet := time.FixedZone("CET", 3600)
+	base := time.Date(2022, time.July, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		opts     []func(*Options)
+		a, b     time.Time
+		wantDesc []StructuredDiff
+	}{
+		{
+			name: "monotonic reading ignored",
+			a:    time.Now(),
+		},
+		{
+			name: "location differs by default",
+			a:    base,
+			b:    base.In(cet),
+			wantDesc: []StructuredDiff{{
+				FieldName: "At",
+				Labels:    []Label{},
+				ValueA:    "2022-07-01 10:00:00 +0000 UTC",
+				ValueB:    "2022-07-01 11:00:00 +0100 CET",
+				A:         base,
+				B:         base.In(cet),
+				Path:      Path{derefStep, fieldStep("At")},
+			}},
+		},
+		{
+			name: "instants",
+			opts: []func(*Options){WithTimeInstants(true)},
+			a:    base,
+			b:    base.In(cet),
+		},
+		{
+			name: "tolerance",
+			opts: []func(*Options){WithTimeTolerance(time.Second)},
+			a:    base,
+			b:    base.Add(-time.Second),
+		},
+		{
+			name: "tolerance exceeded",
+			opts: []func(*Options){WithTimeTolerance(time.Second)},
+			a:    base,
+			b:    base.Add(1001 * time.Millisecond),
+			wantDesc: []StructuredDiff{{
+				FieldName: "At",
+				Labels:    []Label{},
+				ValueA:    "2022-07-01 10:00:00 +0000 UTC",
+				ValueB:    "2022-07-01 10:00:01.001 +0000 UTC",
+				A:         base,
+				B:         base.Add(1001 * time.Millisecond),
+				Path:      Path{derefStep, fieldStep("At")},
+			}},
+		},
+		{
+			name: "truncate",
+			opts: []func(*Options){WithTimeTruncate(time.Minute)},
+			a:    base.Add(10 * time.Second),
+			b:    base.Add(50 * time.Second),
+		},
+		{
+			name: "ignore location",
+			opts: []func(*Options){WithIgnoreTimeLocation(true)},
+			a:    base,
+			b:    time.Date(2022, time.July, 1, 10, 0, 0, 0, cet),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := tt.b
+			if b.IsZero() {
+				b = tt.a.Round(0)
+			}
+			gotDesc, gotOk := NewCustomDiff(tt.opts...).StructuredDiff(&event{tt.a}, &event{b})
+			assert.Equal(t, tt.wantDesc, gotDesc)
+			assert.Equal(t, len(tt.wantDesc) == 0, gotOk)
+		})
+	}
+}
+
+func TestDiffUnexportedTime(t *testing.T) {
+	type event struct {
+		at time.Time
+	}
+	t1 := time.Date(2022, time.January, 1, 0, 0, 0, 0, time.UTC)
+	t2 := t1.Add(time.Hour)
+	// Unexported times cannot be read as time.Time, so their fields are
+	// compared, whether or not they are addressable.
+	want := []string{"at.ext: 63776592000 != 63776595600"}
+	assert.Equal(t, want, Diff(event{t1}, event{t2}))
+	assert.Equal(t, want, Diff(&event{t1}, &event{t2}))
+}
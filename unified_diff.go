@@ -0,0 +1,121 @@
+package pretty
+
+import (
+	"fmt"
+	"strings"
+)
+
+// unifiedContext is the number of unchanged lines UnifiedDiff shows
+// around each change.
+const unifiedContext = 3
+
+// UnifiedDiff pretty-prints a and b with "%# v" and returns the
+// differences between the two texts in unified diff format, with a
+// and b as file names. It returns "" if the texts are equal.
+func UnifiedDiff(a, b interface{}) string {
+	return unifiedText("a", "b", Sprint(a)+"\n", Sprint(b)+"\n")
+}
+
+// unifiedText returns the differences between texts a and b, named
+// aName and bName, in unified diff format.
+func unifiedText(aName, bName, a, b string) string {
+	aLines, aComplete := splitLines(a)
+	bLines, bComplete := splitLines(b)
+	// A last line without a newline differs from the same line with one.
+	aEnd := func(i int) bool { return !aComplete && i == len(aLines)-1 }
+	bEnd := func(j int) bool { return !bComplete && j == len(bLines)-1 }
+	script := editScript(len(aLines), len(bLines), maxEditWork, -1, func(i, j int) bool {
+		return aLines[i] == bLines[j] && aEnd(i) == bEnd(j)
+	})
+
+	var sb strings.Builder
+	writeLine := func(prefix, line string, end bool) {
+		sb.WriteString(prefix + line + "\n")
+		if end {
+			sb.WriteString("\\ No newline at end of file\n")
+		}
+	}
+	// aLine and bLine count the lines of a and b before script[done].
+	var done, aLine, bLine int
+	count := func(script []edit) (aCount, bCount int) {
+		for _, e := range script {
+			if e.op != editInsert {
+				aCount++
+			}
+			if e.op != editDelete {
+				bCount++
+			}
+		}
+		return aCount, bCount
+	}
+	for _, h := range hunks(script, unifiedContext) {
+		if sb.Len() == 0 {
+			sb.WriteString("--- " + aName + "\n+++ " + bName + "\n")
+		}
+		aSkipped, bSkipped := count(script[done:h[0]])
+		aLine, bLine = aLine+aSkipped, bLine+bSkipped
+		aCount, bCount := count(script[h[0]:h[1]])
+		fmt.Fprintf(&sb, "@@ -%s +%s @@\n", hunkRange(aLine, aCount), hunkRange(bLine, bCount))
+		for _, e := range script[h[0]:h[1]] {
+			switch e.op {
+			case editMatch:
+				writeLine(" ", aLines[e.a], aEnd(e.a))
+			case editDelete:
+				writeLine("-", aLines[e.a], aEnd(e.a))
+			case editInsert:
+				writeLine("+", bLines[e.b], bEnd(e.b))
+			}
+		}
+		done, aLine, bLine = h[1], aLine+aCount, bLine+bCount
+	}
+	return sb.String()
+}
+
+// splitLines splits text into lines the way diff does, where a newline
+// ends a line rather than starting another one. complete reports whether
+// the last line ends with a newline.
+func splitLines(text string) (lines []string, complete bool) {
+	if text == "" {
+		return nil, true
+	}
+	complete = strings.HasSuffix(text, "\n")
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n"), complete
+}
+
+// hunks returns the ranges [start, end) of script that hold changes
+// together with up to context matching edits around them. Ranges that
+// would overlap or touch are merged.
+func hunks(script []edit, context int) [][2]int {
+	var hs [][2]int
+	for i, e := range script {
+		if e.op == editMatch {
+			continue
+		}
+		start, end := i-context, i+1+context
+		if start < 0 {
+			start = 0
+		}
+		if end > len(script) {
+			end = len(script)
+		}
+		if n := len(hs); n > 0 && start <= hs[n-1][1] {
+			hs[n-1][1] = end
+			continue
+		}
+		hs = append(hs, [2]int{start, end})
+	}
+	return hs
+}
+
+// hunkRange formats the line range of one side of a hunk that follows
+// skipped lines the way diff -u does: an empty range is given by the
+// line before it.
+func hunkRange(skipped, count int) string {
+	switch count {
+	case 0:
+		return fmt.Sprintf("%d,0", skipped)
+	case 1:
+		return fmt.Sprintf("%d", skipped+1)
+	}
+	return fmt.Sprintf("%d,%d", skipped+1, count)
+}
@@ -0,0 +1,76 @@
+package pretty
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	type item struct {
+		Name  string
+		Price float64
+	}
+	type order struct {
+		ID    int
+		Items []item
+		Note  string
+	}
+	a := order{ID: 1, Items: []item{{"a", 1}, {"b", 2}, {"c", 3}, {"d", 4}, {"e", 5}}, Note: "x"}
+	b := order{ID: 1, Items: []item{{"a", 1}, {"b", 2}, {"c", 3.5}, {"d", 4}, {"e", 5}}, Note: "y"}
+
+	assert.Equal(t, "", UnifiedDiff(a, a))
+	assert.Equal(t, `--- a
++++ b
+@@ -3,9 +3,9 @@
+     Items: {
+         {Name:"a", Price:1},
+         {Name:"b", Price:2},
+-        {Name:"c", Price:3},
++        {Name:"c", Price:3.5},
+         {Name:"d", Price:4},
+         {Name:"e", Price:5},
+     },
+-    Note: "x",
++    Note: "y",
+ }
+`, UnifiedDiff(a, b))
+
+	items := func(from, to int) []item {
+		var s []item
+		for i := from; i < to; i++ {
+			s = append(s, item{Name: fmt.Sprint(i), Price: 1})
+		}
+		return s
+	}
+	assert.Equal(t, `--- a
++++ b
+@@ -1,4 +1,5 @@
+ []pretty.item{
++    {Name:"0", Price:1},
+     {Name:"1", Price:1},
+     {Name:"2", Price:1},
+     {Name:"3", Price:1},
+@@ -8,5 +9,4 @@
+     {Name:"7", Price:1},
+     {Name:"8", Price:1},
+     {Name:"9", Price:1},
+-    {Name:"10", Price:1},
+ }
+`, UnifiedDiff(items(1, 11), items(0, 10)))
+}
+
+func TestUnifiedTextNewlines(t *testing.T) {
+	tests := []struct {
+		name, a, b, want string
+	}{
+		{"equal", "x\ny\n", "x\ny\n", ""},
+		{"trailing newline", "x\ny\n", "x\nz\n", "--- a\n+++ b\n@@ -1,2 +1,2 @@\n x\n-y\n+z\n"},
+		{"newline removed", "x\ny\n", "x\ny", "--- a\n+++ b\n@@ -1,2 +1,2 @@\n x\n-y\n+y\n\\ No newline at end of file\n"},
+		{"from empty", "", "x\n", "--- a\n+++ b\n@@ -0,0 +1 @@\n+x\n"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, unifiedText("a", "b", tt.a, tt.b), tt.name)
+	}
+}